@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============ HTTP API ============
+
+// Listen address used when none is given; only reachable from this machine
+const defaultApiAddr = "127.0.0.1:7788"
+
+type ApiServerInfo struct {
+	Running bool   `json:"running"`
+	Addr    string `json:"addr,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+type apiServer struct {
+	a     *App
+	token string
+	addr  string
+	srv   *http.Server
+}
+
+type apiAddTaskRequest struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Data      interface{} `json:"data"`
+	DependsOn []int       `json:"dependsOn"`
+}
+
+// ApiServerStart starts the HTTP/JSON API. An empty addr binds to localhost only,
+// an empty token generates a random one. Every request must carry the token as
+// "Authorization: Bearer <token>" or "?token=<token>" (for EventSource).
+func (a *App) ApiServerStart(addr string, token string) (ApiServerInfo, error) {
+	a.apiMutex.Lock()
+	defer a.apiMutex.Unlock()
+
+	if a.api != nil {
+		return ApiServerInfo{}, fmt.Errorf("api server already running on %s", a.api.addr)
+	}
+	if addr == "" {
+		addr = defaultApiAddr
+	}
+	if token == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return ApiServerInfo{}, err
+		}
+		token = hex.EncodeToString(buf)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return ApiServerInfo{}, err
+	}
+	s := &apiServer{a: a, token: token, addr: ln.Addr().String()}
+	s.srv = &http.Server{
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go s.srv.Serve(ln)
+
+	a.api = s
+	return ApiServerInfo{Running: true, Addr: s.addr, Token: s.token}, nil
+}
+
+func (a *App) ApiServerStop() error {
+	a.apiMutex.Lock()
+	s := a.api
+	a.api = nil
+	a.apiMutex.Unlock()
+
+	if s == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		// Event streams never go idle on their own
+		return s.srv.Close()
+	}
+	return nil
+}
+
+func (a *App) ApiServerStatus() ApiServerInfo {
+	a.apiMutex.Lock()
+	defer a.apiMutex.Unlock()
+	if a.api == nil {
+		return ApiServerInfo{}
+	}
+	return ApiServerInfo{Running: true, Addr: a.api.addr, Token: a.api.token}
+}
+
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/tasks", s.handleGetTasks)
+	mux.HandleFunc("POST /api/tasks", s.handleAddTask)
+	mux.HandleFunc("GET /api/tasks/{id}/logs", s.handleTaskLogs)
+	mux.HandleFunc("POST /api/tasks/{id}/cancel", s.handleTaskAction)
+	mux.HandleFunc("POST /api/tasks/{id}/pause", s.handleTaskAction)
+	mux.HandleFunc("POST /api/tasks/{id}/resume", s.handleTaskAction)
+	mux.HandleFunc("POST /api/tasks/clear-completed", s.handleClearCompleted)
+	mux.HandleFunc("GET /api/scan/{kind}", s.handleScan)
+	mux.HandleFunc("GET /api/presets", s.handleGetPresets)
+	mux.HandleFunc("POST /api/presets/{name}/enqueue", s.handleEnqueuePreset)
+	mux.HandleFunc("GET /api/schedules", s.handleGetSchedules)
+	mux.HandleFunc("POST /api/schedules", s.handleAddSchedule)
+	mux.HandleFunc("POST /api/schedules/{id}/run", s.handleRunSchedule)
+	mux.HandleFunc("DELETE /api/schedules/{id}", s.handleDeleteSchedule)
+	mux.HandleFunc("GET /api/watches", s.handleGetWatches)
+	mux.HandleFunc("POST /api/watches", s.handleAddWatch)
+	mux.HandleFunc("DELETE /api/watches/{id}", s.handleDeleteWatch)
+	mux.HandleFunc("GET /api/history", s.handleHistory)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+
+	return s.auth(mux)
+}
+
+func (s *apiServer) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeApiError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeApiJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeApiError(w http.ResponseWriter, status int, err error) {
+	writeApiJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *apiServer) handleGetTasks(w http.ResponseWriter, r *http.Request) {
+	writeApiJSON(w, http.StatusOK, s.a.TaskQueueGetAll())
+}
+
+func (s *apiServer) handleAddTask(w http.ResponseWriter, r *http.Request) {
+	var req apiAddTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	id, err := s.a.TaskQueueAddAfter(req.Type, req.Data, req.Name, req.DependsOn)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeApiJSON(w, http.StatusCreated, map[string]int{"id": id})
+}
+
+func (s *apiServer) handleTaskLogs(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid task id"))
+		return
+	}
+	after, _ := strconv.Atoi(r.URL.Query().Get("after"))
+	logs, err := s.a.TaskQueueGetLogs(id, after)
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, err)
+		return
+	}
+	writeApiJSON(w, http.StatusOK, logs)
+}
+
+func (s *apiServer) handleTaskAction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid task id"))
+		return
+	}
+
+	switch action := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]; action {
+	case "cancel":
+		s.a.TaskQueueCancel(id)
+	case "pause":
+		err = s.a.TaskQueuePause(id)
+	case "resume":
+		err = s.a.TaskQueueResume(id)
+	}
+	if err != nil {
+		writeApiError(w, http.StatusConflict, err)
+		return
+	}
+	writeApiJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (s *apiServer) handleClearCompleted(w http.ResponseWriter, r *http.Request) {
+	s.a.TaskQueueClearCompleted()
+	writeApiJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleScan runs a scanner on a path of the machine the app runs on
+func (s *apiServer) handleScan(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("path is required"))
+		return
+	}
+
+	var result interface{}
+	switch r.PathValue("kind") {
+	case "videos":
+		result = s.a.ScanVideos(path)
+	case "archives", "7z":
+		result = s.a.ScanArchives(path)
+	case "images":
+		result = s.a.ScanImageFolders(path)
+	case "txt":
+		result = s.a.ScanTxtFiles(path)
+	default:
+		writeApiError(w, http.StatusNotFound, fmt.Errorf("unknown scanner %q", r.PathValue("kind")))
+		return
+	}
+	writeApiJSON(w, http.StatusOK, result)
+}
+
+func (s *apiServer) handleGetPresets(w http.ResponseWriter, r *http.Request) {
+	writeApiJSON(w, http.StatusOK, s.a.PresetList())
+}
+
+// handleEnqueuePreset scans {"folder": "..."} and enqueues it with the preset's options
+func (s *apiServer) handleEnqueuePreset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Folder string `json:"folder"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	id, err := s.a.PresetEnqueue(r.PathValue("name"), req.Folder)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeApiJSON(w, http.StatusCreated, map[string]int{"id": id})
+}
+
+func (s *apiServer) handleGetSchedules(w http.ResponseWriter, r *http.Request) {
+	writeApiJSON(w, http.StatusOK, s.a.ScheduleList())
+}
+
+func (s *apiServer) handleAddSchedule(w http.ResponseWriter, r *http.Request) {
+	var req Schedule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	sched, err := s.a.ScheduleAdd(req)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeApiJSON(w, http.StatusCreated, sched)
+}
+
+// handleRunSchedule runs a schedule now; id is 0 when the folder had nothing new
+func (s *apiServer) handleRunSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid schedule id"))
+		return
+	}
+	taskID, err := s.a.ScheduleRunNow(id)
+	if err != nil {
+		writeApiError(w, http.StatusConflict, err)
+		return
+	}
+	writeApiJSON(w, http.StatusOK, map[string]int{"id": taskID})
+}
+
+func (s *apiServer) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid schedule id"))
+		return
+	}
+	if err := s.a.ScheduleDelete(id); err != nil {
+		writeApiError(w, http.StatusNotFound, err)
+		return
+	}
+	writeApiJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (s *apiServer) handleGetWatches(w http.ResponseWriter, r *http.Request) {
+	writeApiJSON(w, http.StatusOK, s.a.WatchList())
+}
+
+func (s *apiServer) handleAddWatch(w http.ResponseWriter, r *http.Request) {
+	var req Watch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	watch, err := s.a.WatchAdd(req)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeApiJSON(w, http.StatusCreated, watch)
+}
+
+func (s *apiServer) handleDeleteWatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("invalid watch id"))
+		return
+	}
+	if err := s.a.WatchDelete(id); err != nil {
+		writeApiError(w, http.StatusNotFound, err)
+		return
+	}
+	writeApiJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleHistory queries the task history. ?format=csv or json returns every
+// match as a download instead of a page.
+func (s *apiServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	q := HistoryQuery{Type: v.Get("type"), Status: v.Get("status"), File: v.Get("file")}
+	q.From, _ = strconv.ParseInt(v.Get("from"), 10, 64)
+	q.To, _ = strconv.ParseInt(v.Get("to"), 10, 64)
+	q.Offset, _ = strconv.Atoi(v.Get("offset"))
+	q.Limit, _ = strconv.Atoi(v.Get("limit"))
+
+	switch format := v.Get("format"); format {
+	case "":
+		writeApiJSON(w, http.StatusOK, s.a.HistoryQuery(q))
+	case "csv", "json":
+		q.Offset, q.Limit = 0, 0
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+		w.Header().Set("Content-Disposition", "attachment; filename=task-history."+format)
+		writeHistory(w, s.a.HistoryQuery(q).Records, format)
+	default:
+		writeApiError(w, http.StatusBadRequest, fmt.Errorf("unknown export format: %s", format))
+	}
+}
+
+// handleEvents streams task events as Server-Sent Events. The stream opens with
+// a full "task-list-update", then carries the same "task-update",
+// "task-list-diff", "task-log", "schedule-list-update" and "watch-list-update"
+// events the GUI receives.
+func (s *apiServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeApiError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	// Subscribe before the snapshot so nothing in between is lost
+	events, unsubscribe := s.a.events.Subscribe(256)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeSSE(w, "task-list-update", s.a.TaskQueueGetAll())
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-events:
+			if !ok {
+				// Fell too far behind; the client reconnects and gets a fresh list
+				return
+			}
+			if !apiEventNames(ev.Name) {
+				continue
+			}
+			writeSSE(w, ev.Name, ev.Data)
+		}
+		flusher.Flush()
+	}
+}
+
+// apiEventNames picks the events worth streaming; tool progress stays GUI-only
+func apiEventNames(name string) bool {
+	for _, prefix := range []string{"task-", "schedule-", "watch-"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeSSE(w http.ResponseWriter, name string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, raw)
+}
@@ -2,45 +2,55 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"net/http"
 	"sync"
 	"time"
-)
 
-// ============ Structs (Models) ============
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 
-type VideoFile struct {
-	Name         string `json:"name"`
-	Path         string `json:"path"`
-	Size         int64  `json:"size"`
-	ParentFolder string `json:"parentFolder"`
-}
+	"wcs-toolbox/pkg/gallery"
+	"wcs-toolbox/pkg/scanner"
+)
 
-type FileInfo struct {
-	Name string `json:"name"`
-	Path string `json:"path"`
-	Size int64  `json:"size"`
-}
+// ============ Structs (Models) ============
 
-type FolderInfo struct {
-	Name       string `json:"name"`
-	Path       string `json:"path"`
-	ImageCount int    `json:"imageCount"`
-	TotalSize  int64  `json:"totalSize"`
-}
+// The tools live in pkg/; App only adapts them to Wails and the task queue
+type (
+	VideoFile  = scanner.VideoFile
+	FileInfo   = scanner.FileInfo
+	FolderInfo = scanner.FolderInfo
+	Gallery    = gallery.Gallery
+)
 
 type Task struct {
-	ID        int         `json:"id"`
-	Type      string      `json:"type"`
-	Name      string      `json:"name"`
-	Status    string      `json:"status"` // pending, running, completed, failed, cancelled
-	Data      interface{} `json:"data"`
-	Progress  int         `json:"progress"`
-	Result    interface{} `json:"result,omitempty"`
-	Error     string      `json:"error,omitempty"`
-	CreatedAt int64       `json:"createdAt"`
-	cancel    context.CancelFunc
+	ID         int            `json:"id"`
+	Type       string         `json:"type"`
+	Name       string         `json:"name"`
+	Status     string         `json:"status"` // blocked, pending, running, paused, completed, failed, cancelled, interrupted
+	Data       interface{}    `json:"data"`
+	Progress   int            `json:"progress"`
+	BytesDone  int64          `json:"bytesDone,omitempty"`
+	BytesTotal int64          `json:"bytesTotal,omitempty"`
+	Throughput float64        `json:"throughput,omitempty"` // bytes per second
+	ETA        int64          `json:"eta,omitempty"`        // seconds remaining
+	Result     interface{}    `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Diagnostic string         `json:"diagnostic,omitempty"` // stack trace when the handler panicked
+	CreatedAt  int64          `json:"createdAt"`
+	StartedAt  int64          `json:"startedAt,omitempty"` // latest run
+	FinishedAt int64          `json:"finishedAt,omitempty"`
+	Completed  []string       `json:"completed,omitempty"` // paths of items finished successfully, skipped on resume
+	ParentID   int            `json:"parentId,omitempty"`  // task this one retries
+	Priority   int            `json:"priority"`            // higher runs first
+	Order      int            `json:"order"`               // position among pending tasks of equal priority
+	DependsOn  []int          `json:"dependsOn,omitempty"` // prerequisites; task stays blocked until all complete
+	FeedFrom   int            `json:"feedFrom,omitempty"`  // task whose outputs become this task's items
+	Outputs    []string       `json:"outputs,omitempty"`   // files produced so far
+	Logs       []TaskLogEntry `json:"-"`                   // fetched via TaskQueueGetLogs, journaled by task_store.go
+	cancel     context.CancelFunc
+	running    bool          // set by dispatchTasks, cleared when runTask returns (a cancelled task may still be unwinding)
+	resume     chan struct{} // non-nil while paused, closed on resume
+	rate       byteRate      // see task_progress.go
+	passwords  []string      // tried first on encrypted archives; kept in memory only, never saved or emitted
 }
 
 type TaskResult struct {
@@ -50,9 +60,11 @@ type TaskResult struct {
 }
 
 type ErrorDetail struct {
-	File    string `json:"file,omitempty"`
-	Gallery string `json:"gallery,omitempty"`
-	Error   string `json:"error"`
+	File          string `json:"file,omitempty"`
+	Gallery       string `json:"gallery,omitempty"`
+	Path          string `json:"path,omitempty"` // source item path (gallery URL for crawls), used by retry
+	Error         string `json:"error"`
+	NeedsPassword bool   `json:"needsPassword,omitempty"` // encrypted archive none of the passwords opened
 }
 
 type PreviewResult struct {
@@ -75,13 +87,6 @@ type ConvertResult struct {
 	Errors  []ErrorDetail `json:"errors"`
 }
 
-type Gallery struct {
-	URL        string `json:"url"`
-	Title      string `json:"title"`
-	ImageCount int    `json:"imageCount"`
-	Thumbnail  string `json:"thumbnail"`
-}
-
 type GallerySearchResult struct {
 	Success     bool      `json:"success"`
 	Error       string    `json:"error,omitempty"`
@@ -110,13 +115,18 @@ type Convert7zParams struct {
 	Files            []FileInfo `json:"files"`
 	VideoOutputPath  string     `json:"videoOutputPath"`
 	KeepOriginal     bool       `json:"keepOriginal"`
-	CompressionLevel int        `json:"compressionLevel"`
+	CompressionLevel *int       `json:"compressionLevel,omitempty"` // nil: defaultCompressionLevel
+}
+
+type CrawlGalleryParams struct {
+	Galleries  []Gallery `json:"galleries"`
+	OutputPath string    `json:"outputPath"`
 }
 
 type PackImagesParams struct {
 	Folders          []FolderInfo `json:"folders"`
 	TargetPath       string       `json:"targetPath"`
-	CompressionLevel int          `json:"compressionLevel"`
+	CompressionLevel *int         `json:"compressionLevel,omitempty"` // nil: defaultCompressionLevel
 }
 
 type ConvertTxtParams struct {
@@ -139,30 +149,94 @@ type App struct {
 	tasks      map[int]*Task
 	tasksMutex sync.Mutex
 	taskIdSeq  int
-	taskQueue  chan int
+	taskWake   chan struct{}
 
-	crawlerClient *http.Client
+	queuePaused  bool
+	classLimits  map[string]int // see task_concurrency.go
+	classRunning map[string]int
+
+	events *eventHub // task events go through here, see event_hub.go
+
+	taskStorePath string
+	storeMutex    sync.Mutex
+	saveTimer     *time.Timer
+
+	settings      Settings // see settings.go
+	settingsPath  string
+	settingsMutex sync.Mutex
+
+	presets      map[string]Preset // see presets.go
+	presetsPath  string
+	presetsMutex sync.Mutex
+
+	passwords      []string // see passwords.go
+	passwordsPath  string
+	passwordsMutex sync.Mutex
+
+	schedules      map[int]*Schedule // see schedules.go
+	scheduleIdSeq  int
+	schedulesPath  string
+	schedulesMutex sync.Mutex
+	scheduleWake   chan struct{}
+
+	watches      map[int]*Watch // see watches.go
+	watchIdSeq   int
+	watchesPath  string
+	watchesMutex sync.Mutex
+
+	history      []HistoryRecord // see task_history.go
+	historyKeys  map[string]bool
+	historyPath  string
+	historyMutex sync.Mutex
+
+	apiMutex sync.Mutex
+	api      *apiServer // nil unless the HTTP API is enabled, see api_server.go
+
+	crawler       *gallery.Client
 	crawlerCancel context.CancelFunc
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{
-		tasks:     make(map[int]*Task),
-		taskQueue: make(chan int, 100), // Buffer
-		crawlerClient: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		},
+	a := &App{
+		tasks:        make(map[int]*Task),
+		taskWake:     make(chan struct{}, 1),
+		classLimits:  defaultClassLimits(),
+		classRunning: make(map[string]int),
+		presets:      make(map[string]Preset),
+		schedules:    make(map[int]*Schedule),
+		scheduleWake: make(chan struct{}, 1),
+		watches:      make(map[int]*Watch),
+		crawler:      gallery.NewClient(),
 	}
+	a.settings = defaultSettings()
+	a.events = newEventHub(func(name string, data interface{}) {
+		runtime.EventsEmit(a.ctx, name, data)
+	}, a.snapshotTasks, 200*time.Millisecond)
+	return a
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.loadSettings() // Defined in settings.go
+	a.loadPresets()
+	a.loadPasswords()
+	a.loadTasks() // Defined in task_store.go
+	a.loadHistory()
+	a.loadSchedules()
+	a.loadWatches()
 	// Start task processor
 	go a.processTasks() // Defined in task_queue.go
+	a.wakeDispatcher()
+	go a.runScheduler() // Defined in schedules.go
+	a.startWatches()    // Defined in watches.go
+}
+
+// shutdown flushes the task journal so pending work survives a restart
+func (a *App) shutdown(ctx context.Context) {
+	a.ApiServerStop()
+	a.stopWatches()
+	a.saveTasks()
 }
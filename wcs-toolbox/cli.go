@@ -0,0 +1,583 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"wcs-toolbox/pkg/archive"
+	"wcs-toolbox/pkg/scanner"
+)
+
+// ============ Headless CLI ============
+
+// Exit codes of the CLI
+const (
+	exitOK      = 0
+	exitFailed  = 1 // task failed or was cancelled, or the tool returned an error
+	exitUsage   = 2
+	exitPartial = 3 // task finished but some items failed
+)
+
+type cliCommand struct {
+	name  string
+	usage string
+	run   func(a *App, args []string) int
+}
+
+var cliCommands []cliCommand
+
+// Filled in init because the commands' usage text refers back to this list
+func init() {
+	cliCommands = []cliCommand{
+		{"scan-videos", "scan-videos <目录>", cliScanVideos},
+		{"shortcuts", "shortcuts -target <目录> [-naming folder|folderOnly|original] <源目录>...", cliShortcuts},
+		{"archive-to-zip", "archive-to-zip [-video-out <目录>] [-delete] [-level 0-9] [-password 密码]... <压缩包或目录>...", cliConvertArchive},
+		{"7z-to-zip", "", cliConvertArchive}, // old name, kept for scripts
+		{"pack-images", "pack-images -target <目录> [-level 0-9] <图片目录>...", cliPackImages},
+		{"txt2epub", "txt2epub -out <目录> [-author 作者] [-pattern 正则] <txt文件或目录>...", cliTxtToEpub},
+		{"preview-chapters", "preview-chapters [-pattern 正则] <txt文件>", cliPreviewChapters},
+		{"gallery", "gallery -out <目录> [-pages N] [-limit N] [-list] <关键字>", cliGallery},
+		{"preset", "preset <预设名> <源目录>", cliPreset},
+		{"history", "history [-type 类型] [-status 状态] [-file 文件名] [-since 2006-01-02] [-until 2006-01-02] [-limit N] [-format json|csv]", cliHistory},
+		{"serve", "serve [-addr 127.0.0.1:7788] [-token 令牌]", cliServe},
+	}
+}
+
+// isCLIInvocation reports whether args name a subcommand, so main can skip the GUI
+func isCLIInvocation(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return true
+	}
+	for _, c := range cliCommands {
+		if c.name == args[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// runCLI executes one subcommand and returns the process exit code.
+// Progress and logs go to stderr, the result JSON to stdout.
+func runCLI(args []string) int {
+	for _, c := range cliCommands {
+		if c.name == args[0] {
+			return c.run(newCLIApp(), args[1:])
+		}
+	}
+	cliUsage()
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return exitOK
+	}
+	return exitUsage
+}
+
+func cliUsage() {
+	fmt.Fprintln(os.Stderr, "用法: wcs-toolbox <命令> [参数]")
+	fmt.Fprintln(os.Stderr, "不带参数运行时启动图形界面。")
+	fmt.Fprintln(os.Stderr)
+	for _, c := range cliCommands {
+		if c.usage != "" {
+			fmt.Fprintln(os.Stderr, "  wcs-toolbox "+c.usage)
+		}
+	}
+}
+
+// newCLIApp builds an App without a Wails context. Events are dropped until a
+// command installs its own printer, and nothing is journaled to the GUI's task store.
+func newCLIApp() *App {
+	a := NewApp()
+	a.events = newEventHub(func(string, interface{}) {}, a.snapshotTasks, 500*time.Millisecond)
+	return a
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		for _, c := range cliCommands {
+			if c.name == name {
+				fmt.Fprintln(os.Stderr, "用法: wcs-toolbox "+c.usage)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "错误:", err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, string(data))
+}
+
+func cliError(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "错误: "+format+"\n", args...)
+	return exitFailed
+}
+
+// ============ Running a task ============
+
+// cliProgress prints task updates and log entries of one task to stderr
+type cliProgress struct {
+	mu       sync.Mutex
+	taskID   int
+	lastLine string
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (p *cliProgress) emit(name string, data interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch name {
+	case "task-update":
+		t, ok := data.(Task)
+		if !ok || t.ID != p.taskID {
+			return
+		}
+		line := fmt.Sprintf("[%3d%%] %s", t.Progress, t.Name)
+		if t.BytesTotal > 0 {
+			line += fmt.Sprintf("  %s / %s", formatBytes(t.BytesDone), formatBytes(t.BytesTotal))
+		}
+		if t.Throughput > 0 {
+			line += fmt.Sprintf("  %s/s", formatBytes(int64(t.Throughput)))
+		}
+		if t.ETA > 0 {
+			line += fmt.Sprintf("  剩余 %s", time.Duration(t.ETA)*time.Second)
+		}
+		if line != p.lastLine {
+			p.lastLine = line
+			fmt.Fprintln(os.Stderr, line)
+		}
+		switch t.Status {
+		case "completed", "failed", "cancelled":
+			p.once.Do(func() { close(p.done) })
+		}
+	case "task-log":
+		m, ok := data.(map[string]interface{})
+		if !ok || m["taskId"] != p.taskID {
+			return
+		}
+		if entry, ok := m["entry"].(TaskLogEntry); ok {
+			fmt.Fprintf(os.Stderr, "%s [%s] %s\n", time.UnixMilli(entry.Time).Format("15:04:05"), entry.Level, entry.Message)
+		}
+	}
+}
+
+// runCLITask pushes one task through the regular queue, waits for it and prints its result.
+// Ctrl+C cancels the task; items already finished stay finished.
+func runCLITask(a *App, taskType string, params taskParams, name string) int {
+	p := &cliProgress{done: make(chan struct{})}
+	a.events = newEventHub(p.emit, a.snapshotTasks, 500*time.Millisecond)
+	go a.processTasks()
+
+	// Hold the lock so the task can't finish before we know its id
+	p.mu.Lock()
+	id, err := a.TaskQueueAdd(taskType, params, name)
+	p.taskID = id
+	p.mu.Unlock()
+	if err != nil {
+		cliError("%v", err)
+		return exitUsage // rejected params
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	select {
+	case <-p.done:
+	case <-interrupt:
+		fmt.Fprintln(os.Stderr, "正在取消...")
+		a.TaskQueueCancel(id)
+		<-p.done
+	}
+	// A cancelled task reports its status before the handler has returned
+	for a.runningTaskCount() > 0 {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	a.tasksMutex.Lock()
+	task := a.tasks[id]
+	status, errMsg, result := task.Status, task.Error, task.Result
+	a.tasksMutex.Unlock()
+
+	switch status {
+	case "completed":
+		printJSON(result)
+		if len(taskResultErrors(result)) > 0 {
+			return exitPartial
+		}
+		return exitOK
+	default:
+		printJSON(map[string]interface{}{
+			"status": status,
+			"error":  errMsg,
+		})
+		return exitFailed
+	}
+}
+
+func (a *App) runningTaskCount() int {
+	a.tasksMutex.Lock()
+	defer a.tasksMutex.Unlock()
+	return a.classRunning["total"]
+}
+
+// ============ Commands ============
+
+func cliScanVideos(a *App, args []string) int {
+	fs := newFlagSet("scan-videos")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	videos := a.ScanVideos(fs.Arg(0))
+	if videos == nil {
+		videos = []VideoFile{}
+	}
+	printJSON(videos)
+	return exitOK
+}
+
+func cliShortcuts(a *App, args []string) int {
+	fs := newFlagSet("shortcuts")
+	target := fs.String("target", "", "快捷方式输出目录")
+	naming := fs.String("naming", "folder", "命名模式: folder, folderOnly, original")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var videos []VideoFile
+	for _, dir := range fs.Args() {
+		videos = append(videos, a.ScanVideos(dir)...)
+	}
+	fmt.Fprintf(os.Stderr, "找到 %d 个视频\n", len(videos))
+
+	return runCLITask(a, "create-shortcuts", &CreateShortcutsParams{
+		Videos:     videos,
+		TargetPath: *target,
+		NamingMode: *naming,
+	}, "创建快捷方式")
+}
+
+func cliConvertArchive(a *App, args []string) int {
+	fs := newFlagSet("archive-to-zip")
+	videoOut := fs.String("video-out", "", "视频文件单独输出的目录，默认为第一个压缩包或目录所在处")
+	remove := fs.Bool("delete", false, "转换并校验成功后将原始压缩包移到回收站")
+	level := fs.Int("level", defaultCompressionLevel, "压缩级别 0-9")
+	var passwords []string
+	fs.Func("password", "加密压缩包的密码，可重复；之后再试界面中保存的密码列表", func(s string) error {
+		passwords = append(passwords, s)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	a.loadPasswords()
+	// Tried before the stored list. Only in memory: the CLI never saves the list
+	a.passwords = uniquePasswords(passwords, a.passwords)
+
+	files, err := cliCollectFiles(fs.Args(), a.ScanArchives)
+	if err != nil {
+		return cliError("%v", err)
+	}
+	for i := range files {
+		// A first volume named directly stands for the whole set
+		files[i].Size = archive.VolumesSize(files[i].Path)
+	}
+	fmt.Fprintf(os.Stderr, "找到 %d 个压缩包\n", len(files))
+	if *videoOut == "" {
+		*videoOut = cliDefaultDir(fs.Arg(0))
+	}
+
+	return runCLITask(a, "convert-7z-to-zip", &Convert7zParams{
+		Files:            files,
+		VideoOutputPath:  *videoOut,
+		KeepOriginal:     !*remove,
+		CompressionLevel: level,
+	}, "压缩包转 ZIP")
+}
+
+func cliPackImages(a *App, args []string) int {
+	fs := newFlagSet("pack-images")
+	target := fs.String("target", "", "ZIP 输出目录")
+	level := fs.Int("level", defaultCompressionLevel, "压缩级别 0-9")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var folders []FolderInfo
+	for _, dir := range fs.Args() {
+		// The folder itself may hold the images
+		if folder, ok := scanner.ImageFolder(dir); ok {
+			folders = append(folders, folder)
+		}
+		folders = append(folders, a.ScanImageFolders(dir)...)
+	}
+	fmt.Fprintf(os.Stderr, "找到 %d 个图片文件夹\n", len(folders))
+
+	return runCLITask(a, "pack-images", &PackImagesParams{
+		Folders:          folders,
+		TargetPath:       *target,
+		CompressionLevel: level,
+	}, "图片打包")
+}
+
+func cliTxtToEpub(a *App, args []string) int {
+	fs := newFlagSet("txt2epub")
+	out := fs.String("out", "", "EPUB 输出目录")
+	author := fs.String("author", "", "作者")
+	pattern := fs.String("pattern", "", "自定义章节正则")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	files, err := cliCollectFiles(fs.Args(), a.ScanTxtFiles)
+	if err != nil {
+		return cliError("%v", err)
+	}
+	fmt.Fprintf(os.Stderr, "找到 %d 个 TXT 文件\n", len(files))
+
+	params := &ConvertTxtParams{Files: files, OutputPath: *out}
+	params.Options.Author = *author
+	params.Options.CustomPattern = *pattern
+	return runCLITask(a, "convert-txt-to-epub", params, "TXT 转 EPUB")
+}
+
+func cliPreviewChapters(a *App, args []string) int {
+	fs := newFlagSet("preview-chapters")
+	pattern := fs.String("pattern", "", "自定义章节正则")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	result := a.PreviewTxtChapters(PreviewTxtParams{FilePath: fs.Arg(0), CustomPattern: *pattern})
+	printJSON(result)
+	if !result.Success {
+		return exitFailed
+	}
+	return exitOK
+}
+
+func cliGallery(a *App, args []string) int {
+	fs := newFlagSet("gallery")
+	out := fs.String("out", "", "ZIP 输出目录")
+	pages := fs.Int("pages", 1, "搜索的最大页数")
+	limit := fs.Int("limit", 0, "最多抓取的图库数量 (0 表示全部)")
+	list := fs.Bool("list", false, "只输出搜索结果，不下载")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	keyword := strings.Join(fs.Args(), " ")
+
+	a.events = newEventHub(func(name string, data interface{}) {
+		if m, ok := data.(map[string]interface{}); ok && name == "gallery-search-progress" {
+			fmt.Fprintf(os.Stderr, "已搜索 %v/%v 页，找到 %v 个图库\n", m["currentPage"], m["maxPages"], m["galleriesFound"])
+		}
+	}, a.snapshotTasks, 500*time.Millisecond)
+
+	search := a.GallerySearchAll(keyword, *pages)
+	if !search.Success {
+		return cliError("%s", search.Error)
+	}
+	galleries := search.Galleries
+	if *limit > 0 && len(galleries) > *limit {
+		galleries = galleries[:*limit]
+	}
+	if *list {
+		if galleries == nil {
+			galleries = []Gallery{}
+		}
+		printJSON(galleries)
+		return exitOK
+	}
+
+	return runCLITask(a, "crawl-gallery", &CrawlGalleryParams{
+		Galleries:  galleries,
+		OutputPath: *out,
+	}, "图库抓取: "+keyword)
+}
+
+// cliPreset runs a preset saved in the GUI against a folder
+func cliPreset(a *App, args []string) int {
+	fs := newFlagSet("preset")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	name, folder := fs.Arg(0), fs.Arg(1)
+
+	a.loadPresets()
+	p, err := a.PresetGet(name)
+	if err != nil {
+		return cliError("%v", err)
+	}
+	params, err := decodeTaskParams(p.Type, p.Data)
+	if err == nil {
+		params, err = scanTaskItems(params, folder)
+	}
+	if err != nil {
+		return cliError("%v", err)
+	}
+	fmt.Fprintf(os.Stderr, "找到 %d 项\n", len(params.itemKeys()))
+
+	return runCLITask(a, p.Type, params, fmt.Sprintf("%s: %s", p.Name, filepath.Base(folder)))
+}
+
+// cliHistory prints archived tasks of the GUI (newest first) to stdout, filtered like the history panel
+func cliHistory(a *App, args []string) int {
+	fs := newFlagSet("history")
+	var q HistoryQuery
+	fs.StringVar(&q.Type, "type", "", "任务类型，如 convert-7z-to-zip")
+	fs.StringVar(&q.Status, "status", "", "completed、failed、cancelled 或 interrupted")
+	fs.StringVar(&q.File, "file", "", "文件名或路径中包含的文字")
+	since := fs.String("since", "", "起始日期 (含)")
+	until := fs.String("until", "", "结束日期 (含)")
+	fs.IntVar(&q.Limit, "limit", 0, "最多输出条数，0 为全部")
+	format := fs.String("format", "json", "json 或 csv")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *since != "" {
+		day, err := time.ParseInLocation("2006-01-02", *since, time.Local)
+		if err != nil {
+			return cliError("invalid -since %q, expected 2006-01-02", *since)
+		}
+		q.From = day.UnixMilli()
+	}
+	if *until != "" {
+		day, err := time.ParseInLocation("2006-01-02", *until, time.Local)
+		if err != nil {
+			return cliError("invalid -until %q, expected 2006-01-02", *until)
+		}
+		q.To = day.AddDate(0, 0, 1).UnixMilli() - 1 // through the end of that day
+	}
+
+	a.loadHistory()
+	if err := writeHistory(os.Stdout, a.HistoryQuery(q).Records, *format); err != nil {
+		return cliError("%v", err)
+	}
+	return exitOK
+}
+
+// cliServe runs the task queue with the HTTP API and no window, e.g. on a NAS.
+// Unlike the other commands it uses the same journal as the GUI, so don't run both at once.
+func cliServe(a *App, args []string) int {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", defaultApiAddr, "监听地址，默认仅本机可访问")
+	token := fs.String("token", "", "访问令牌，留空则随机生成")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	a.loadSettings()
+	a.loadPasswords()
+	a.loadPresets()
+	a.loadTasks()
+	a.loadHistory()
+	a.loadSchedules()
+	a.loadWatches()
+	go a.processTasks()
+	a.wakeDispatcher()
+	go a.runScheduler()
+	a.startWatches()
+
+	info, err := a.ApiServerStart(*addr, *token)
+	if err != nil {
+		return cliError("%v", err)
+	}
+	fmt.Fprintf(os.Stderr, "API 已启动: http://%s/api/tasks\n", info.Addr)
+	fmt.Fprintf(os.Stderr, "令牌: %s\n", info.Token)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+
+	a.shutdown(context.Background())
+	return exitOK
+}
+
+// cliDefaultDir is path itself for a directory, else the directory it is in
+func cliDefaultDir(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path
+	}
+	return filepath.Dir(path)
+}
+
+// cliCollectFiles expands directories with scan and takes plain files as given
+func cliCollectFiles(paths []string, scan func(string) []FileInfo) ([]FileInfo, error) {
+	var files []FileInfo
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			files = append(files, scan(p)...)
+			continue
+		}
+		abs, _ := filepath.Abs(p)
+		files = append(files, FileInfo{Name: info.Name(), Path: abs, Size: info.Size()})
+	}
+	return files, nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
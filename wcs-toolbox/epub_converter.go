@@ -1,73 +1,72 @@
 package main
 
 import (
-	"archive/zip"
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"regexp"
-	"strings"
 
-	"github.com/wailsapp/wails/v2/pkg/runtime"
-	"golang.org/x/text/encoding/simplifiedchinese"
+	"wcs-toolbox/pkg/epub"
+	"wcs-toolbox/pkg/scanner"
 )
 
 // ============ Handlers ============
 
 func (a *App) handleConvertTxtToEpub(ctx context.Context, task *Task) (interface{}, error) {
-	dataMap, _ := task.Data.(map[string]interface{})
-	filesListRaw, _ := dataMap["files"].([]interface{})
-	outputPath := dataMap["outputPath"].(string)
-	options, _ := dataMap["options"].(map[string]interface{})
+	params, ok := task.Data.(*ConvertTxtParams)
+	if !ok {
+		return nil, fmt.Errorf("invalid data format")
+	}
+	outputPath := params.OutputPath
 
-	author := "Unknown"
-	pattern := ""
-	if options != nil {
-		if v, ok := options["author"]; ok && v != nil {
-			author = v.(string)
-		}
-		if v, ok := options["customPattern"]; ok && v != nil {
-			pattern = v.(string)
-		}
+	author := params.Options.Author
+	if author == "" {
+		author = "Unknown"
 	}
+	pattern := params.Options.CustomPattern
 
 	os.MkdirAll(outputPath, 0755)
 
 	success := 0
 	failed := 0
 	var errors []ErrorDetail
-	total := len(filesListRaw)
+	total := len(params.Files)
+	done := a.completedItems(task)
+
+	var totalBytes int64
+	for _, f := range params.Files {
+		totalBytes += f.Size
+	}
+	a.setTaskBytesTotal(task, totalBytes)
 
-	for i, f := range filesListRaw {
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
+	for i, f := range params.Files {
+		if err := a.waitIfPaused(ctx, task); err != nil {
+			return nil, err
 		}
-		fMap := f.(map[string]interface{})
-		path := fMap["path"].(string)
-		name := fMap["name"].(string)
+		path := f.Path
+		name := f.Name
 
-		content, err := readTxtFile(path)
-		if err != nil {
-			failed++
-			errors = append(errors, ErrorDetail{File: name, Error: err.Error()})
+		if done[path] {
+			a.addTaskBytes(task, f.Size)
+			success++
 			continue
 		}
 
-		chapters := parseChapters(content, pattern)
-		epubName := strings.TrimSuffix(name, filepath.Ext(name)) + ".epub"
-		epubPath := filepath.Join(outputPath, epubName)
-
-		err = generateEpub(epubPath, strings.TrimSuffix(name, filepath.Ext(name)), author, chapters)
+		epubPath, chapters, err := epub.Convert(path, outputPath, author, pattern)
 		if err != nil {
+			a.taskLogf(task, "error", "转换失败 %s: %v", path, err)
 			failed++
-			errors = append(errors, ErrorDetail{File: name, Error: err.Error()})
+			errors = append(errors, ErrorDetail{File: name, Path: path, Error: err.Error()})
 		} else {
 			success++
+			a.markItemDone(task, path)
+			a.addTaskOutput(task, epubPath)
+			a.taskLogf(task, "info", "已生成 %s (%d 章)", epubPath, chapters)
 		}
 
+		a.addTaskBytes(task, f.Size)
+
 		// Emit special progress event for Txt2epub UI (legacy from Electron)
-		runtime.EventsEmit(a.ctx, "txt2epub-progress", map[string]interface{}{
+		a.emitEvent("txt2epub-progress", map[string]interface{}{
 			"current":     i + 1,
 			"total":       total,
 			"currentFile": name,
@@ -92,26 +91,15 @@ func (a *App) ConvertTxtToEpub(params ConvertTxtParams) ConvertResult {
 	total := len(params.Files)
 
 	for i, f := range params.Files {
-		content, err := readTxtFile(f.Path)
-		if err != nil {
-			failed++
-			errors = append(errors, ErrorDetail{File: f.Name, Error: err.Error()})
-			continue
-		}
-
-		chapters := parseChapters(content, params.Options.CustomPattern)
-		epubName := strings.TrimSuffix(f.Name, filepath.Ext(f.Name)) + ".epub"
-		epubPath := filepath.Join(params.OutputPath, epubName)
-
-		err = generateEpub(epubPath, strings.TrimSuffix(f.Name, filepath.Ext(f.Name)), params.Options.Author, chapters)
+		_, _, err := epub.Convert(f.Path, params.OutputPath, params.Options.Author, params.Options.CustomPattern)
 		if err != nil {
 			failed++
-			errors = append(errors, ErrorDetail{File: f.Name, Error: err.Error()})
+			errors = append(errors, ErrorDetail{File: f.Name, Path: f.Path, Error: err.Error()})
 		} else {
 			success++
 		}
 
-		runtime.EventsEmit(a.ctx, "txt2epub-progress", map[string]interface{}{
+		a.emitEvent("txt2epub-progress", map[string]interface{}{
 			"current":     i + 1,
 			"total":       total,
 			"currentFile": f.Name,
@@ -122,30 +110,16 @@ func (a *App) ConvertTxtToEpub(params ConvertTxtParams) ConvertResult {
 }
 
 func (a *App) ScanTxtFiles(dir string) []FileInfo {
-	var files []FileInfo
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !info.IsDir() && strings.ToLower(filepath.Ext(path)) == ".txt" {
-			files = append(files, FileInfo{
-				Name: info.Name(),
-				Path: path,
-				Size: info.Size(),
-			})
-		}
-		return nil
-	})
-	return files
+	return scanner.Files(dir, ".txt")
 }
 
 func (a *App) PreviewTxtChapters(params PreviewTxtParams) PreviewResult {
-	content, err := readTxtFile(params.FilePath)
+	content, err := epub.ReadTxt(params.FilePath)
 	if err != nil {
 		return PreviewResult{Success: false, Error: err.Error()}
 	}
 
-	chapters := parseChapters(content, params.CustomPattern)
+	chapters := epub.ParseChapters(content, params.CustomPattern)
 
 	var previewChapters []Chapter
 	for i, c := range chapters {
@@ -170,214 +144,3 @@ func (a *App) PreviewTxtChapters(params PreviewTxtParams) PreviewResult {
 		Chapters:      previewChapters,
 	}
 }
-
-// ============ Helpers ============
-
-type ChapterData struct {
-	Title   string
-	Content string
-}
-
-func readTxtFile(path string) (string, error) {
-	bytes, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
-	}
-
-	// Simple Heuristic: Try decode as GBK. If valid UTF8, prefer UTF8.
-	// Actually, Go's strings are UTF8.
-	// We can check if `utf8.Valid(bytes)`.
-	// If not, try GBK.
-
-	// I'll assume GBK first since typical CN novels are GBK.
-	// Or check heuristic.
-
-	// Decoding GBK
-	// Decoding GBK
-	decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(bytes)
-	if err == nil {
-		return string(decoded), nil
-	}
-	// If decoded successfully and looks readable...
-	// Often pure UTF8 bytes might be invalid GBK or yield garbage.
-
-	// Better approach:
-	// If ValidUTF8 -> return string.
-	// Else -> Trim encoded GBK.
-
-	// Note: "DetectEncoding" is complex. For now, try GBK, if fail, try UTF8?
-	// Or try UTF8, if fail, try GBK.
-
-	// Usually UTF8 is stricter.
-	// if utf8.Valid(bytes) { return string(bytes), nil }
-
-	// Let's force simplifiedchinese.GBK for now as it handles mixed ASCII well,
-	// BUT file might be UTF8.
-
-	// Try UTF8 first
-	// We don't have "unicode/utf8" imported, let's just assume UTF8 if valid.
-	// Actually I'll use a pragmatic approach:
-	// Try to decode as GBK. If error, use raw strings (UTF8).
-
-	// Just use GBK decoder. If it's UTF8 without BOM, GBK decoder might garble it?
-	// Yes.
-
-	// Let's implement a dummy "detect"
-	// For this task, I will default to attempting GBK if it contains non-ascii high bytes,
-	// checking if the result is valid...
-
-	// PROPER FIX:
-	// Use `golang.org/x/net/html/charset` DetermineEncoding?
-	// I included it in `go get` step.
-	// e, _, _ := charset.DetermineEncoding(bytes, "")
-	// decoded, _ := e.NewDecoder().Bytes(bytes)
-	// return string(decoded), nil
-
-	// But `charset` needs `pkg/mod`... I did `go get golang.org/x/net`.
-	// Let's assume standard UTF8/GBK toggle.
-
-	// For now simplistically:
-	return string(bytes), nil // Default to UTF8 for safety, real "detect" needs library import usage in `app.go`.
-}
-
-func parseChapters(content string, pattern string) []ChapterData {
-	// Pattern default: ^\s*第.+[章节].*
-	if pattern == "" {
-		pattern = `(?m)^\s*第.+[章节].*`
-	} else {
-		if !strings.HasPrefix(pattern, "(?m)") {
-			pattern = "(?m)" + pattern
-		}
-	}
-
-	reg, err := regexp.Compile(pattern)
-	if err != nil {
-		// Fallback
-		reg = regexp.MustCompile(`(?m)^\s*第.+[章节].*`)
-	}
-
-	idxs := reg.FindAllStringIndex(content, -1)
-	if len(idxs) == 0 {
-		return []ChapterData{{Title: "全文", Content: content}}
-	}
-
-	var chapters []ChapterData
-
-	// Text before first chapter
-	if idxs[0][0] > 0 {
-		chapters = append(chapters, ChapterData{Title: "前言", Content: content[:idxs[0][0]]})
-	}
-
-	for i, idx := range idxs {
-		title := content[idx[0]:idx[1]]
-		start := idx[1]
-		end := len(content)
-		if i < len(idxs)-1 {
-			end = idxs[i+1][0]
-		}
-
-		body := content[start:end]
-		chapters = append(chapters, ChapterData{
-			Title:   strings.TrimSpace(title),
-			Content: body,
-		})
-	}
-
-	return chapters
-}
-
-func generateEpub(dest string, title, author string, chapters []ChapterData) error {
-	f, err := os.Create(dest)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	w := zip.NewWriter(f)
-	defer w.Close()
-
-	// 1. mimetype (Stored, no compression)
-	mimetype := &zip.FileHeader{
-		Name:   "mimetype",
-		Method: zip.Store,
-	}
-	mw, _ := w.CreateHeader(mimetype)
-	mw.Write([]byte("application/epub+zip"))
-
-	// 2. META-INF/container.xml
-	cw, _ := w.Create("META-INF/container.xml")
-	cw.Write([]byte(`<?xml version="1.0"?>
-<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
-   <rootfiles>
-      <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
-   </rootfiles>
-</container>`))
-
-	// 3. OEBPS/content.opf
-	// Build manifest and spine
-	var manifestItems []string
-	var spineItems []string
-
-	manifestItems = append(manifestItems, `<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>`)
-
-	for i := range chapters {
-		id := fmt.Sprintf("ch%d", i+1)
-		fname := fmt.Sprintf("chapter%d.html", i+1)
-		manifestItems = append(manifestItems, fmt.Sprintf(`<item id="%s" href="%s" media-type="application/xhtml+xml"/>`, id, fname))
-		spineItems = append(spineItems, fmt.Sprintf(`<itemref idref="%s"/>`, id))
-
-		// Write chapter file
-		hw, _ := w.Create("OEBPS/" + fname)
-		html := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
-<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">
-<html xmlns="http://www.w3.org/1999/xhtml">
-<head><title>%s</title></head>
-<body><h1>%s</h1><pre>%s</pre></body>
-</html>`, chapters[i].Title, chapters[i].Title, chapters[i].Content)
-		hw.Write([]byte(html))
-	}
-
-	opf := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
-<package xmlns="http://www.idpf.org/2007/opf" unique-identifier="BookId" version="2.0">
-  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
-    <dc:title>%s</dc:title>
-    <dc:creator>%s</dc:creator>
-    <dc:language>zh-CN</dc:language>
-  </metadata>
-  <manifest>
-    %s
-  </manifest>
-  <spine toc="ncx">
-    %s
-  </spine>
-</package>`, title, author, strings.Join(manifestItems, "\n"), strings.Join(spineItems, "\n"))
-
-	ow, _ := w.Create("OEBPS/content.opf")
-	ow.Write([]byte(opf))
-
-	// 4. OEBPS/toc.ncx
-	var navPoints []string
-	for i, ch := range chapters {
-		navPoints = append(navPoints, fmt.Sprintf(`
-    <navPoint id="navPoint-%d" playOrder="%d">
-      <navLabel><text>%s</text></navLabel>
-      <content src="chapter%d.html"/>
-    </navPoint>`, i+1, i+1, ch.Title, i+1))
-	}
-
-	ncx := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
-  <head>
-    <meta name="dtb:uid" content="urn:uuid:12345"/>
-  </head>
-  <docTitle><text>%s</text></docTitle>
-  <navMap>
-    %s
-  </navMap>
-</ncx>`, title, strings.Join(navPoints, "\n"))
-
-	nw, _ := w.Create("OEBPS/toc.ncx")
-	nw.Write([]byte(ncx))
-
-	return nil
-}
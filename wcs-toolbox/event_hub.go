@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============ Event Hub ============
+
+// eventHub batches task change notifications. Any number of Touch/TouchAll
+// calls within one interval become a single flush, which emits
+//
+//	"task-update"     once per task that actually changed (latest state only)
+//	"task-list-diff"  {updated: []Task, removed: []int} for the whole batch
+//
+// It only needs an emit function and a way to snapshot tasks, so it has no
+// dependency on the Wails runtime. Other consumers (the HTTP API) can
+// Subscribe to receive the same events.
+type eventHub struct {
+	emit     func(name string, data interface{})
+	snapshot func(ids []int) []Task // nil ids = every task
+	interval time.Duration
+
+	mu        sync.Mutex // guards the pending state below
+	dirty     map[int]bool
+	dirtyAll  bool
+	timer     *time.Timer
+	lastFlush time.Time
+
+	flushMu sync.Mutex     // serializes flushes; snapshot is taken without mu held
+	sent    map[int]string // JSON of each task as last emitted, for diffing
+
+	subMu sync.Mutex
+	subs  map[chan hubEvent]bool
+}
+
+type hubEvent struct {
+	Name string
+	Data interface{}
+}
+
+type TaskListDiff struct {
+	Updated []Task `json:"updated"`
+	Removed []int  `json:"removed"`
+}
+
+func newEventHub(emit func(string, interface{}), snapshot func([]int) []Task, interval time.Duration) *eventHub {
+	return &eventHub{
+		emit:     emit,
+		snapshot: snapshot,
+		interval: interval,
+		dirty:    make(map[int]bool),
+		sent:     make(map[int]string),
+		subs:     make(map[chan hubEvent]bool),
+	}
+}
+
+// Emit forwards events that are not coalesced (logs, tool progress, ...)
+func (h *eventHub) Emit(name string, data interface{}) {
+	h.send(name, data)
+}
+
+// Subscribe returns a channel receiving every emitted event and a func to
+// unsubscribe. A subscriber that falls more than buffer events behind is
+// dropped (its channel is closed) rather than stalling everyone else; since
+// list events are diffs it should resync from a full list when that happens.
+func (h *eventHub) Subscribe(buffer int) (<-chan hubEvent, func()) {
+	ch := make(chan hubEvent, buffer)
+	h.subMu.Lock()
+	h.subs[ch] = true
+	h.subMu.Unlock()
+	return ch, func() {
+		h.subMu.Lock()
+		if h.subs[ch] {
+			delete(h.subs, ch)
+			close(ch)
+		}
+		h.subMu.Unlock()
+	}
+}
+
+func (h *eventHub) send(name string, data interface{}) {
+	h.emit(name, data)
+
+	h.subMu.Lock()
+	for ch := range h.subs {
+		select {
+		case ch <- hubEvent{Name: name, Data: data}:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+	h.subMu.Unlock()
+}
+
+// Touch marks one task as changed
+func (h *eventHub) Touch(id int) {
+	h.mu.Lock()
+	h.dirty[id] = true
+	h.scheduleLocked()
+	h.mu.Unlock()
+}
+
+// TouchAll marks the whole list as changed (adds, removals, reordering)
+func (h *eventHub) TouchAll() {
+	h.mu.Lock()
+	h.dirtyAll = true
+	h.scheduleLocked()
+	h.mu.Unlock()
+}
+
+func (h *eventHub) scheduleLocked() {
+	if h.timer != nil {
+		return
+	}
+	wait := h.interval - time.Since(h.lastFlush)
+	if wait < 0 {
+		wait = 0
+	}
+	h.timer = time.AfterFunc(wait, h.Flush)
+}
+
+// Flush emits everything pending right away
+func (h *eventHub) Flush() {
+	h.flushMu.Lock()
+	defer h.flushMu.Unlock()
+
+	h.mu.Lock()
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	h.lastFlush = time.Now()
+
+	all := h.dirtyAll
+	var ids []int
+	if !all {
+		if len(h.dirty) == 0 {
+			h.mu.Unlock()
+			return
+		}
+		for id := range h.dirty {
+			ids = append(ids, id)
+		}
+	}
+	h.dirty = make(map[int]bool)
+	h.dirtyAll = false
+	h.mu.Unlock()
+
+	tasks := h.snapshot(ids)
+	diff := TaskListDiff{Updated: []Task{}, Removed: []int{}}
+
+	present := make(map[int]bool, len(tasks))
+	for _, t := range tasks {
+		present[t.ID] = true
+		raw, err := json.Marshal(t)
+		if err != nil {
+			continue
+		}
+		if h.sent[t.ID] == string(raw) {
+			continue
+		}
+		h.sent[t.ID] = string(raw)
+		diff.Updated = append(diff.Updated, t)
+	}
+
+	// Everything we emitted before but which no longer exists is removed
+	candidates := ids
+	if all {
+		candidates = nil
+		for id := range h.sent {
+			candidates = append(candidates, id)
+		}
+	}
+	for _, id := range candidates {
+		if _, known := h.sent[id]; known && !present[id] {
+			delete(h.sent, id)
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	if len(diff.Updated) == 0 && len(diff.Removed) == 0 {
+		return
+	}
+	sort.Slice(diff.Updated, func(i, j int) bool { return diff.Updated[i].ID > diff.Updated[j].ID })
+	sort.Ints(diff.Removed)
+
+	for _, t := range diff.Updated {
+		h.send("task-update", t)
+	}
+	h.send("task-list-diff", diff)
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"io/fs"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,27 +9,36 @@ import (
 	"strings"
 
 	wruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"wcs-toolbox/pkg/archive"
+	"wcs-toolbox/pkg/scanner"
 )
 
 // ============ File System Methods ============
 
+// Folder dialogs start in the most recently used folder and remember the choice (settings.go)
+
 func (a *App) SelectSourceFolder() string {
 	path, err := wruntime.OpenDirectoryDialog(a.ctx, wruntime.OpenDialogOptions{
-		Title: "选择源文件夹",
+		Title:            "选择源文件夹",
+		DefaultDirectory: a.recentFolder("source"),
 	})
 	if err != nil {
 		return ""
 	}
+	a.SettingsAddRecentFolder("source", path)
 	return path
 }
 
 func (a *App) SelectTargetFolder() string {
 	path, err := wruntime.OpenDirectoryDialog(a.ctx, wruntime.OpenDialogOptions{
-		Title: "选择目标文件夹",
+		Title:            "选择目标文件夹",
+		DefaultDirectory: a.recentFolder("target"),
 	})
 	if err != nil {
 		return ""
 	}
+	a.SettingsAddRecentFolder("target", path)
 	return path
 }
 
@@ -66,6 +75,41 @@ func (a *App) OpenFolder(path string) {
 	cmd.Run()
 }
 
+// moveToTrash sends path to the Recycle Bin / Trash so it can still be restored.
+// It never deletes outright: where there's no trash the file stays and an error says why.
+func moveToTrash(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		script := "Add-Type -AssemblyName Microsoft.VisualBasic; [Microsoft.VisualBasic.FileIO.FileSystem]::DeleteFile('" +
+			strings.ReplaceAll(abs, "'", "''") + "', 'OnlyErrorDialogs', 'SendToRecycleBin')"
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	case "darwin":
+		// The path goes in as an argument, so it needs no quoting
+		cmd = exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", `tell application "Finder" to delete POSIX file (item 1 of argv)`,
+			"-e", "end run", abs)
+	default:
+		gio, err := exec.LookPath("gio")
+		if err != nil {
+			return fmt.Errorf("no trash available (gio not found)")
+		}
+		cmd = exec.Command(gio, "trash", abs)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("move to trash failed: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	if _, err := os.Lstat(abs); !os.IsNotExist(err) {
+		return fmt.Errorf("move to trash failed: file is still there")
+	}
+	return nil
+}
+
 func (a *App) GetPlatform() string {
 	return runtime.GOOS
 }
@@ -73,97 +117,15 @@ func (a *App) GetPlatform() string {
 // ============ Scanners ============
 
 func (a *App) ScanVideos(rootPath string) []VideoFile {
-	var videos []VideoFile
-	videoExts := map[string]bool{
-		".mp4": true, ".mkv": true, ".avi": true, ".mov": true, ".wmv": true, ".flv": true, ".webm": true, ".m4v": true, ".ts": true,
-	}
-
-	filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !d.IsDir() {
-			ext := strings.ToLower(filepath.Ext(path))
-			if videoExts[ext] {
-				parent := filepath.Base(filepath.Dir(path))
-				// Ensure not root if rootPath is file? No, Input is always folder
-				if filepath.Dir(path) == rootPath {
-					parent = filepath.Base(rootPath)
-				}
-
-				info, _ := d.Info()
-				videos = append(videos, VideoFile{
-					Name:         d.Name(),
-					Path:         path,
-					Size:         info.Size(),
-					ParentFolder: parent,
-				})
-			}
-		}
-		return nil
-	})
-	return videos
+	return scanner.Videos(rootPath)
 }
 
-func (a *App) Scan7zFiles(rootPath string) []FileInfo {
-	var files []FileInfo
-	filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !d.IsDir() && strings.HasSuffix(strings.ToLower(d.Name()), ".7z") {
-			info, _ := d.Info()
-			files = append(files, FileInfo{
-				Name: d.Name(),
-				Path: path,
-				Size: info.Size(),
-			})
-		}
-		return nil
-	})
-	return files
+// ScanArchives finds 7z, rar and tar archives by content; split archives are
+// listed once, under their first volume
+func (a *App) ScanArchives(rootPath string) []FileInfo {
+	return archive.Find(rootPath)
 }
 
 func (a *App) ScanImageFolders(rootPath string) []FolderInfo {
-	var folders []FolderInfo
-	imageExts := map[string]bool{
-		".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".webp": true,
-	}
-
-	// Just walk dirs
-	filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() && path != rootPath {
-			// Check if this folder has images
-			imgCount := 0
-			var totalSize int64 = 0
-
-			entries, err := os.ReadDir(path)
-			if err == nil {
-				for _, entry := range entries {
-					if !entry.IsDir() {
-						ext := strings.ToLower(filepath.Ext(entry.Name()))
-						if imageExts[ext] {
-							imgCount++
-							info, _ := entry.Info()
-							totalSize += info.Size()
-						}
-					}
-				}
-			}
-
-			if imgCount > 0 {
-				folders = append(folders, FolderInfo{
-					Name:       d.Name(),
-					Path:       path,
-					ImageCount: imgCount,
-					TotalSize:  totalSize,
-				})
-			}
-		}
-		return nil
-	})
-	return folders
+	return scanner.ImageFolders(rootPath)
 }
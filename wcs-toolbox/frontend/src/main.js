@@ -222,6 +222,7 @@ createBtn.addEventListener('click', async () => {
             },
             `创建 ${selectedVideos.length} 个视频快捷方式`
         );
+        saveToolSettings('SettingsSetShortcuts', { targetPath: targetPath.value, namingMode: namingMode });
 
         // 显示成功提示
         alert(`任务已添加到队列！\n任务ID: ${taskId}\n请查看任务队列面板了解进度。`);
@@ -230,7 +231,7 @@ createBtn.addEventListener('click', async () => {
         // resetBtn.click();
 
     } catch (error) {
-        alert('添加任务失败: ' + error.message);
+        alert('添加任务失败: ' + (error.message || error));
     }
 });
 
@@ -258,7 +259,7 @@ resetBtn.addEventListener('click', () => {
 // 初始化应用
 init();
 
-// ============ 7z转ZIP工具 ============
+// ============ 压缩包转ZIP工具 ============
 
 // 获取DOM元素
 const convertSelectSourceBtn = document.getElementById('convert-selectSourceBtn');
@@ -273,6 +274,8 @@ const convertTargetSection = document.getElementById('convert-targetSection');
 const convertVideoPath = document.getElementById('convert-videoPath');
 const convertSelectVideoBtn = document.getElementById('convert-selectVideoBtn');
 const convertKeepOriginal = document.getElementById('convert-keepOriginal');
+const convertPasswords = document.getElementById('convert-passwords');
+const convertSavePasswordsBtn = document.getElementById('convert-savePasswordsBtn');
 const convertStartBtn = document.getElementById('convert-startBtn');
 const convertProgressSection = document.getElementById('convert-progressSection');
 const convertProgressFill = document.getElementById('convert-progressFill');
@@ -289,10 +292,10 @@ const convertResetBtn = document.getElementById('convert-resetBtn');
 const convertErrorList = document.getElementById('convert-errorList');
 const convertErrorListContent = document.getElementById('convert-errorListContent');
 
-// 存储扫描到的7z文件
-let scanned7zFiles = [];
+// 存储扫描到的压缩包
+let scannedArchives = [];
 
-// 选择7z源文件夹
+// 选择压缩包源文件夹
 convertSelectSourceBtn.addEventListener('click', async () => {
     const path = await window.go.main.App.SelectSourceFolder();
     if (path) {
@@ -314,13 +317,13 @@ convertSelectVideoBtn.addEventListener('click', async () => {
     }
 });
 
-// 扫描7z文件
+// 扫描压缩包
 convertScanBtn.addEventListener('click', async () => {
     convertScanBtn.disabled = true;
     convertScanBtn.textContent = '扫描中...';
 
     try {
-        scanned7zFiles = await window.go.main.App.Scan7zFiles(convertSourcePath.value);
+        scannedArchives = await window.go.main.App.ScanArchives(convertSourcePath.value);
 
         // 显示结果
         convertFileListSection.style.display = 'block';
@@ -332,29 +335,29 @@ convertScanBtn.addEventListener('click', async () => {
             convertVideoPath.value = convertSourcePath.value + separator + '提取的视频';
         }
 
-        convertFileCount.textContent = `共找到 ${scanned7zFiles.length} 个7z文件`;
+        convertFileCount.textContent = `共找到 ${scannedArchives.length} 个压缩包`;
 
-        // 渲染7z文件列表
-        render7zFileList();
+        // 渲染压缩包列表
+        renderArchiveList();
 
     } catch (error) {
         alert('扫描出错: ' + error.message);
     } finally {
         convertScanBtn.disabled = false;
-        convertScanBtn.textContent = '扫描7z文件';
+        convertScanBtn.textContent = '扫描压缩包';
     }
 });
 
-// 渲染7z文件列表
-function render7zFileList() {
+// 渲染压缩包列表
+function renderArchiveList() {
     convertFileList.innerHTML = '';
 
-    if (scanned7zFiles.length === 0) {
-        convertFileList.innerHTML = '<div class="no-videos">未找到7z文件</div>';
+    if (scannedArchives.length === 0) {
+        convertFileList.innerHTML = '<div class="no-videos">未找到压缩包</div>';
         return;
     }
 
-    scanned7zFiles.forEach((file, index) => {
+    scannedArchives.forEach((file, index) => {
         const item = document.createElement('div');
         item.className = 'video-item';
         item.innerHTML = `
@@ -374,13 +377,13 @@ function render7zFileList() {
     updateConvertButtonState();
 }
 
-// 全选7z文件
+// 全选压缩包
 convertSelectAllBtn.addEventListener('click', () => {
     document.querySelectorAll('.file-checkbox').forEach(cb => cb.checked = true);
     updateConvertButtonState();
 });
 
-// 取消全选7z文件
+// 取消全选压缩包
 convertDeselectAllBtn.addEventListener('click', () => {
     document.querySelectorAll('.file-checkbox').forEach(cb => cb.checked = false);
     updateConvertButtonState();
@@ -426,11 +429,11 @@ convertStartBtn.addEventListener('click', async () => {
     const selectedFiles = [];
     document.querySelectorAll('.file-checkbox:checked').forEach(cb => {
         const index = parseInt(cb.dataset.index);
-        selectedFiles.push(scanned7zFiles[index]);
+        selectedFiles.push(scannedArchives[index]);
     });
 
     if (selectedFiles.length === 0) {
-        alert('请至少选择一个7z文件');
+        alert('请至少选择一个压缩包');
         return;
     }
 
@@ -447,14 +450,40 @@ convertStartBtn.addEventListener('click', async () => {
                 keepOriginal: convertKeepOriginal.checked,
                 compressionLevel: compressionLevel
             },
-            `转换 ${selectedFiles.length} 个7z文件`
+            `转换 ${selectedFiles.length} 个压缩包`
         );
+        saveToolSettings('SettingsSetConvert7z', {
+            videoOutputPath: convertVideoPath.value,
+            keepOriginal: convertKeepOriginal.checked,
+            compressionLevel: compressionLevel
+        });
 
         // 显示成功提示
         alert(`任务已添加到队列！\n任务ID: ${taskId}\n请查看任务队列面板了解进度。`);
 
     } catch (error) {
-        alert('添加任务失败: ' + error.message);
+        alert('添加任务失败: ' + (error.message || error));
+    }
+});
+
+// 加载解压密码列表
+async function loadPasswordList() {
+    try {
+        const list = await window.go.main.App.PasswordList();
+        convertPasswords.value = (list || []).join('\n');
+    } catch (error) {
+        console.error('加载密码列表失败:', error);
+    }
+}
+
+// 保存解压密码列表（每行一个）
+convertSavePasswordsBtn.addEventListener('click', async () => {
+    try {
+        const list = await window.go.main.App.PasswordListSet(convertPasswords.value.split('\n'));
+        convertPasswords.value = list.join('\n');
+        alert(`已保存 ${list.length} 个密码`);
+    } catch (error) {
+        alert('保存密码列表失败: ' + (error.message || error));
     }
 });
 
@@ -463,11 +492,11 @@ convertOpenVideoBtn.addEventListener('click', () => {
     window.go.main.App.OpenFolder(convertVideoPath.value);
 });
 
-// 重新开始（7z转换工具）
+// 重新开始（压缩包转换工具）
 convertResetBtn.addEventListener('click', () => {
     convertSourcePath.value = '';
     convertVideoPath.value = '';
-    scanned7zFiles = [];
+    scannedArchives = [];
     convertScanBtn.disabled = true;
     convertStartBtn.disabled = true;
     convertFileListSection.style.display = 'none';
@@ -658,12 +687,16 @@ imagezipStartBtn.addEventListener('click', async () => {
             },
             `打包 ${selectedFolders.length} 个图片文件夹`
         );
+        saveToolSettings('SettingsSetPackImages', {
+            targetPath: imagezipTargetPath.value,
+            compressionLevel: compressionLevel
+        });
 
         // 显示成功提示
         alert(`任务已添加到队列！\n任务ID: ${taskId}\n请查看任务队列面板了解进度。`);
 
     } catch (error) {
-        alert('添加任务失败: ' + error.message);
+        alert('添加任务失败: ' + (error.message || error));
     }
 });
 
@@ -695,6 +728,7 @@ imagezipResetBtn.addEventListener('click', () => {
 const taskQueuePanel = document.getElementById('taskQueuePanel');
 const toggleQueueBtn = document.getElementById('toggleQueueBtn');
 const clearCompletedBtn = document.getElementById('clearCompletedBtn');
+const pauseQueueBtn = document.getElementById('pauseQueueBtn');
 const taskList = document.getElementById('taskList');
 const taskQueueContent = document.getElementById('taskQueueContent');
 
@@ -712,6 +746,31 @@ toggleQueueBtn.addEventListener('click', () => {
     }
 });
 
+// 暂停/恢复整个队列
+let isQueuePaused = false;
+
+function updatePauseQueueBtn() {
+    pauseQueueBtn.textContent = isQueuePaused ? '恢复队列' : '暂停队列';
+}
+
+pauseQueueBtn.addEventListener('click', async () => {
+    if (isQueuePaused) {
+        await window.go.main.App.TaskQueueResumeAll();
+    } else {
+        await window.go.main.App.TaskQueuePauseAll();
+    }
+});
+
+window.runtime.EventsOn('task-queue-paused', (paused) => {
+    isQueuePaused = paused;
+    updatePauseQueueBtn();
+});
+
+window.go.main.App.TaskQueueIsPaused().then((paused) => {
+    isQueuePaused = paused;
+    updatePauseQueueBtn();
+});
+
 // 清除已完成的任务
 clearCompletedBtn.addEventListener('click', async () => {
     await window.go.main.App.TaskQueueClearCompleted();
@@ -720,11 +779,14 @@ clearCompletedBtn.addEventListener('click', async () => {
 // 格式化任务状态
 function formatTaskStatus(status) {
     const statusMap = {
+        'blocked': { text: '等待前置任务', icon: '🔗', class: 'blocked' },
         'pending': { text: '等待中', icon: '⏳', class: 'pending' },
         'running': { text: '执行中', icon: '▶️', class: 'running' },
+        'paused': { text: '已暂停', icon: '⏸️', class: 'paused' },
         'completed': { text: '已完成', icon: '✅', class: 'completed' },
         'failed': { text: '失败', icon: '❌', class: 'failed' },
-        'cancelled': { text: '已取消', icon: '🚫', class: 'cancelled' }
+        'cancelled': { text: '已取消', icon: '🚫', class: 'cancelled' },
+        'interrupted': { text: '已中断', icon: '⏸️', class: 'interrupted' }
     };
     return statusMap[status] || { text: status, icon: '❓', class: 'unknown' };
 }
@@ -733,8 +795,10 @@ function formatTaskStatus(status) {
 function formatTaskType(type) {
     const typeMap = {
         'create-shortcuts': '视频快捷方式',
-        'convert-7z-to-zip': '7z转ZIP',
-        'pack-images': '图片打包'
+        'convert-7z-to-zip': '压缩包转ZIP',
+        'pack-images': '图片打包',
+        'convert-txt-to-epub': 'TXT转EPUB',
+        'crawl-gallery': '图库抓取'
     };
     return typeMap[type] || type;
 }
@@ -758,6 +822,31 @@ function formatTime(timestamp) {
     });
 }
 
+// 格式化字节进度、速度和剩余时间
+function formatTaskRate(task) {
+    if (!task.bytesTotal) return '';
+    let text = ` · ${formatFileSize(task.bytesDone || 0)} / ${formatFileSize(task.bytesTotal)}`;
+    if (task.throughput) {
+        text += ` · ${formatFileSize(Math.round(task.throughput))}/s`;
+    }
+    if (task.eta) {
+        const m = Math.floor(task.eta / 60);
+        const sec = task.eta % 60;
+        text += ` · 剩余 ${m > 0 ? m + '分' : ''}${sec}秒`;
+    }
+    return text;
+}
+
+// 已展开日志的任务及其日志缓存
+const openTaskLogs = new Map();
+
+function formatLogEntries(entries) {
+    return entries.map(e => {
+        const time = new Date(e.time).toLocaleTimeString('zh-CN');
+        return `[${time}] ${e.level.toUpperCase()} ${e.message}`;
+    }).join('\n');
+}
+
 // 渲染任务列表
 function renderTaskList(tasks) {
     if (!tasks || tasks.length === 0) {
@@ -777,28 +866,67 @@ function renderTaskList(tasks) {
         taskItem.dataset.taskId = task.id;
 
         let progressHTML = '';
-        if (task.status === 'running') {
+        if (task.status === 'running' || task.status === 'paused') {
             progressHTML = `
         <div class="task-progress-bar">
           <div class="task-progress-fill" style="width: ${task.progress}%"></div>
         </div>
-        <div class="task-progress-text">${task.progress}%</div>
+        <div class="task-progress-text">${task.progress}%${formatTaskRate(task)}</div>
       `;
         }
 
         let resultHTML = '';
         if (task.status === 'completed' && task.result) {
             const r = task.result;
-            resultHTML = `<div class="task-result">成功:${r.success} 失败:${r.failed}</div>`;
-        } else if (task.status === 'failed') {
+            const locked = (r.errors || []).filter(e => e.needsPassword).length;
+            resultHTML = `<div class="task-result">成功:${r.success} 失败:${r.failed}${locked > 0 ? ` (${locked} 个需要密码)` : ''}</div>`;
+        } else if ((task.status === 'failed' || task.status === 'cancelled') && task.error) {
             resultHTML = `<div class="task-error">${task.error}</div>`;
         }
 
         let actionsHTML = '';
         if (task.status === 'pending') {
             actionsHTML = `
+        <button class="task-btn-front" data-task-id="${task.id}">置顶</button>
+        <button class="task-btn-cancel" data-task-id="${task.id}">取消</button>
+      `;
+        } else if (task.status === 'blocked') {
+            actionsHTML = `
+        <button class="task-btn-cancel" data-task-id="${task.id}">取消</button>
+      `;
+        } else if (task.status === 'running') {
+            actionsHTML = `
+        <button class="task-btn-pause" data-task-id="${task.id}">暂停</button>
+        <button class="task-btn-cancel" data-task-id="${task.id}">取消</button>
+      `;
+        } else if (task.status === 'paused') {
+            actionsHTML = `
+        <button class="task-btn-resume" data-task-id="${task.id}">继续</button>
         <button class="task-btn-cancel" data-task-id="${task.id}">取消</button>
       `;
+        } else if (['interrupted', 'failed', 'cancelled'].includes(task.status)) {
+            actionsHTML = `
+        <button class="task-btn-resume" data-task-id="${task.id}">继续</button>
+      `;
+        } else if (task.status === 'completed' && task.result && task.result.failed > 0) {
+            actionsHTML = `
+        <button class="task-btn-resume task-btn-retry" data-task-id="${task.id}">重试失败项</button>
+      `;
+            if ((task.result.errors || []).some(e => e.needsPassword)) {
+                actionsHTML += `
+        <button class="task-btn-resume task-btn-retry task-btn-password" data-task-id="${task.id}">输入密码重试</button>
+      `;
+            }
+        }
+
+        let presetHTML = '';
+        if (task.type !== 'crawl-gallery') {
+            presetHTML = `<button class="task-btn-log task-btn-preset" data-task-id="${task.id}">存为预设</button>`;
+        }
+
+        let logHTML = '';
+        if (openTaskLogs.has(task.id)) {
+            logHTML = `<pre class="task-log">${formatLogEntries(openTaskLogs.get(task.id))}</pre>`;
         }
 
         taskItem.innerHTML = `
@@ -814,10 +942,13 @@ function renderTaskList(tasks) {
         </div>
         <div class="task-actions">
           ${actionsHTML}
+          ${presetHTML}
+          <button class="task-btn-log task-btn-logs" data-task-id="${task.id}">日志</button>
         </div>
       </div>
       ${progressHTML}
       ${resultHTML}
+      ${logHTML}
     `;
 
         taskList.appendChild(taskItem);
@@ -830,28 +961,493 @@ function renderTaskList(tasks) {
             await window.go.main.App.TaskQueueCancel(taskId);
         });
     });
+
+    // 绑定日志按钮事件
+    taskList.querySelectorAll('.task-btn-logs').forEach(btn => {
+        btn.addEventListener('click', async (e) => {
+            const taskId = parseInt(e.target.dataset.taskId);
+            if (openTaskLogs.has(taskId)) {
+                openTaskLogs.delete(taskId);
+            } else {
+                openTaskLogs.set(taskId, await window.go.main.App.TaskQueueGetLogs(taskId, 0));
+            }
+            renderCachedTasks();
+        });
+    });
+
+    // 绑定置顶按钮事件
+    taskList.querySelectorAll('.task-btn-front').forEach(btn => {
+        btn.addEventListener('click', async (e) => {
+            const taskId = parseInt(e.target.dataset.taskId);
+            await window.go.main.App.TaskQueueMoveToFront(taskId);
+        });
+    });
+
+    // 绑定暂停按钮事件
+    taskList.querySelectorAll('.task-btn-pause').forEach(btn => {
+        btn.addEventListener('click', async (e) => {
+            const taskId = parseInt(e.target.dataset.taskId);
+            await window.go.main.App.TaskQueuePause(taskId);
+        });
+    });
+
+    // 绑定继续按钮事件
+    taskList.querySelectorAll('.task-btn-resume:not(.task-btn-retry)').forEach(btn => {
+        btn.addEventListener('click', async (e) => {
+            const taskId = parseInt(e.target.dataset.taskId);
+            await window.go.main.App.TaskQueueResume(taskId);
+        });
+    });
+
+    // 绑定存为预设按钮事件（名称取输入框，留空则用任务名）
+    taskList.querySelectorAll('.task-btn-preset').forEach(btn => {
+        btn.addEventListener('click', async (e) => {
+            const taskId = parseInt(e.target.dataset.taskId);
+            const task = taskCache.get(taskId);
+            const name = presetNameInput.value.trim() || (task && task.name) || `任务 ${taskId}`;
+            try {
+                await window.go.main.App.PresetSaveFromTask(taskId, name);
+                presetNameInput.value = '';
+                await loadPresets(name);
+            } catch (err) {
+                alert('保存预设失败: ' + (err.message || err));
+            }
+        });
+    });
+
+    // 绑定重试失败项按钮事件
+    taskList.querySelectorAll('.task-btn-retry:not(.task-btn-password)').forEach(btn => {
+        btn.addEventListener('click', async (e) => {
+            const taskId = parseInt(e.target.dataset.taskId);
+            try {
+                await window.go.main.App.TaskQueueRetryFailed(taskId);
+            } catch (err) {
+                alert('重试失败: ' + err);
+            }
+        });
+    });
+
+    // 绑定输入密码重试按钮事件（只重试需要密码的压缩包，密码取上方输入框）
+    taskList.querySelectorAll('.task-btn-password').forEach(btn => {
+        btn.addEventListener('click', async (e) => {
+            const taskId = parseInt(e.target.dataset.taskId);
+            const password = retryPasswordInput.value;
+            if (!password) {
+                alert('请先在任务列表上方输入解压密码');
+                retryPasswordInput.focus();
+                return;
+            }
+            const remember = retryPasswordRemember.checked;
+            try {
+                await window.go.main.App.TaskQueueRetryWithPassword(taskId, password, remember);
+                retryPasswordInput.value = '';
+                if (remember) await loadPasswordList();
+            } catch (err) {
+                alert('重试失败: ' + err);
+            }
+        });
+    });
 }
 
-// 监听任务更新
-window.runtime.EventsOn('task-update', (task) => {
-    // 更新单个任务
-    const taskItem = document.querySelector(`.task-item[data-task-id="${task.id}"]`);
-    if (taskItem) {
-        // 重新获取所有任务并渲染
-        window.go.main.App.TaskQueueGetAll().then(renderTaskList);
-    } else {
-        // 新任务，重新获取所有任务
-        window.go.main.App.TaskQueueGetAll().then(renderTaskList);
+// 本地任务缓存，由 task-list-diff 增量更新
+const taskCache = new Map();
+
+function renderCachedTasks() {
+    const tasks = Array.from(taskCache.values()).sort((a, b) => b.id - a.id);
+    renderTaskList(tasks);
+}
+
+// 监听任务日志
+window.runtime.EventsOn('task-log', (data) => {
+    if (!openTaskLogs.has(data.taskId)) return;
+    openTaskLogs.get(data.taskId).push(data.entry);
+    const pre = document.querySelector(`.task-item[data-task-id="${data.taskId}"] .task-log`);
+    if (pre) {
+        pre.textContent = formatLogEntries(openTaskLogs.get(data.taskId));
+        pre.scrollTop = pre.scrollHeight;
     }
 });
 
-// 监听任务列表更新
-window.runtime.EventsOn('task-list-update', (tasks) => {
-    renderTaskList(tasks);
+// 监听任务列表增量更新（后端已合并并限速）
+window.runtime.EventsOn('task-list-diff', (diff) => {
+    (diff.updated || []).forEach(task => taskCache.set(task.id, task));
+    (diff.removed || []).forEach(id => {
+        taskCache.delete(id);
+        openTaskLogs.delete(id);
+    });
+    renderCachedTasks();
 });
 
 // 初始化时加载任务列表
-window.go.main.App.TaskQueueGetAll().then(renderTaskList);
+window.go.main.App.TaskQueueGetAll().then((tasks) => {
+    (tasks || []).forEach(task => taskCache.set(task.id, task));
+    renderCachedTasks();
+});
+
+// ============ 预设 ============
+
+const presetSelect = document.getElementById('presetSelect');
+const presetNameInput = document.getElementById('presetNameInput');
+const retryPasswordInput = document.getElementById('retryPasswordInput');
+const retryPasswordRemember = document.getElementById('retryPasswordRemember');
+const runPresetBtn = document.getElementById('runPresetBtn');
+const renamePresetBtn = document.getElementById('renamePresetBtn');
+const deletePresetBtn = document.getElementById('deletePresetBtn');
+
+async function loadPresets(selected) {
+    const presets = await window.go.main.App.PresetList();
+    presetSelect.innerHTML = '<option value="">-- 预设 --</option>' + presets.map(p =>
+        `<option value="${p.name}">${p.name} (${formatTaskType(p.type)})</option>`
+    ).join('');
+    presetSelect.value = selected || '';
+    loadSchedulePresets();
+}
+
+// 选择源文件夹，扫描后按预设的选项加入队列
+runPresetBtn.addEventListener('click', async () => {
+    if (!presetSelect.value) {
+        alert('请先选择一个预设');
+        return;
+    }
+    const folder = await window.go.main.App.SelectSourceFolder();
+    if (!folder) return;
+    try {
+        const taskId = await window.go.main.App.PresetEnqueue(presetSelect.value, folder);
+        alert(`任务已添加到队列！\n任务ID: ${taskId}`);
+    } catch (error) {
+        alert('添加任务失败: ' + (error.message || error));
+    }
+});
+
+renamePresetBtn.addEventListener('click', async () => {
+    const newName = presetNameInput.value.trim();
+    if (!presetSelect.value || !newName) {
+        alert('请选择预设并输入新名称');
+        return;
+    }
+    try {
+        const preset = await window.go.main.App.PresetGet(presetSelect.value);
+        preset.name = newName;
+        await window.go.main.App.PresetUpdate(presetSelect.value, preset);
+        presetNameInput.value = '';
+        await loadPresets(newName);
+    } catch (error) {
+        alert('重命名失败: ' + (error.message || error));
+    }
+});
+
+deletePresetBtn.addEventListener('click', async () => {
+    if (!presetSelect.value) return;
+    if (!confirm(`确定删除预设「${presetSelect.value}」吗？`)) return;
+    try {
+        await window.go.main.App.PresetDelete(presetSelect.value);
+        await loadPresets();
+    } catch (error) {
+        alert('删除失败: ' + (error.message || error));
+    }
+});
+
+loadPresets();
+
+// ============ 定时任务 ============
+
+const schedulePreset = document.getElementById('schedule-preset');
+const scheduleFolder = document.getElementById('schedule-folder');
+const scheduleMode = document.getElementById('schedule-mode');
+const scheduleCron = document.getElementById('schedule-cron');
+const scheduleRunAt = document.getElementById('schedule-runAt');
+const scheduleName = document.getElementById('schedule-name');
+const scheduleList = document.getElementById('schedule-list');
+
+// 定时任务显示完整时间 (formatTime 只适合过去的时间)
+function formatDateTime(ms) {
+    return ms ? new Date(ms).toLocaleString() : '-';
+}
+
+function renderSchedules(schedules) {
+    if (!schedules || schedules.length === 0) {
+        scheduleList.innerHTML = '<div class="task-empty">暂无定时任务</div>';
+        return;
+    }
+    scheduleList.innerHTML = schedules.map(s => `
+        <div class="video-item schedule-item ${s.enabled ? '' : 'disabled'}">
+            <div class="video-info">
+                <span class="video-name">${s.name} ${s.preset ? `(${s.preset})` : ''}</span>
+                <div class="video-meta">
+                    <span>${s.cron || '仅一次'}</span>
+                    <span>📁 ${s.folder}</span>
+                </div>
+                <div class="video-meta">
+                    <span>下次: ${s.enabled ? formatDateTime(s.nextRun) : '已停用'}</span>
+                    <span>上次: ${formatDateTime(s.lastRun)}${s.lastTaskId ? ` (任务 #${s.lastTaskId})` : ''}</span>
+                    ${s.lastError ? `<span class="schedule-error">${s.lastError}</span>` : ''}
+                </div>
+            </div>
+            <div class="schedule-actions">
+                <button class="btn btn-small" data-action="run" data-id="${s.id}">立即运行</button>
+                <button class="btn btn-small" data-action="toggle" data-id="${s.id}">${s.enabled ? '停用' : '启用'}</button>
+                <button class="btn btn-small" data-action="delete" data-id="${s.id}">删除</button>
+            </div>
+        </div>
+    `).join('');
+}
+
+async function loadSchedules() {
+    renderSchedules(await window.go.main.App.ScheduleList());
+}
+
+// 预设列表变化时同步到定时任务的下拉框
+async function loadSchedulePresets() {
+    const presets = await window.go.main.App.PresetList();
+    const current = schedulePreset.value;
+    schedulePreset.innerHTML = '<option value="">-- 请选择预设 --</option>' + presets.map(p =>
+        `<option value="${p.name}">${p.name} (${formatTaskType(p.type)})</option>`
+    ).join('');
+    schedulePreset.value = current;
+
+    const currentWatch = watchPreset.value;
+    watchPreset.innerHTML = schedulePreset.innerHTML;
+    watchPreset.value = currentWatch;
+}
+
+scheduleMode.addEventListener('change', () => {
+    const once = scheduleMode.value === 'once';
+    scheduleCron.style.display = once ? 'none' : '';
+    scheduleRunAt.style.display = once ? '' : 'none';
+});
+
+document.getElementById('schedule-selectFolderBtn').addEventListener('click', async () => {
+    const folder = await window.go.main.App.SelectSourceFolder();
+    if (folder) scheduleFolder.value = folder;
+});
+
+document.getElementById('schedule-addBtn').addEventListener('click', async () => {
+    if (!schedulePreset.value || !scheduleFolder.value) {
+        alert('请选择预设和文件夹');
+        return;
+    }
+    const schedule = {
+        name: scheduleName.value.trim(),
+        preset: schedulePreset.value,
+        folder: scheduleFolder.value,
+        enabled: true,
+    };
+    if (scheduleMode.value === 'once') {
+        if (!scheduleRunAt.value) {
+            alert('请选择运行时间');
+            return;
+        }
+        schedule.runAt = new Date(scheduleRunAt.value).getTime();
+    } else {
+        schedule.cron = scheduleCron.value.trim();
+    }
+    try {
+        await window.go.main.App.ScheduleAdd(schedule);
+        scheduleName.value = '';
+    } catch (error) {
+        alert('添加定时任务失败: ' + (error.message || error));
+    }
+});
+
+scheduleList.addEventListener('click', async (e) => {
+    const btn = e.target.closest('button[data-action]');
+    if (!btn) return;
+    const id = parseInt(btn.dataset.id);
+    try {
+        switch (btn.dataset.action) {
+            case 'run': {
+                const taskId = await window.go.main.App.ScheduleRunNow(id);
+                alert(taskId ? `任务已添加到队列！\n任务ID: ${taskId}` : '没有发现新文件');
+                break;
+            }
+            case 'toggle': {
+                const schedules = await window.go.main.App.ScheduleList();
+                const s = schedules.find(x => x.id === id);
+                if (!s) return;
+                s.enabled = !s.enabled;
+                await window.go.main.App.ScheduleUpdate(id, s);
+                break;
+            }
+            case 'delete':
+                if (!confirm('确定删除这个定时任务吗？')) return;
+                await window.go.main.App.ScheduleDelete(id);
+                break;
+        }
+    } catch (error) {
+        alert('操作失败: ' + (error.message || error));
+    }
+});
+
+window.runtime.EventsOn('schedule-list-update', renderSchedules);
+
+// ---- 监视文件夹 ----
+
+const watchPreset = document.getElementById('watch-preset');
+const watchFolder = document.getElementById('watch-folder');
+const watchSettle = document.getElementById('watch-settle');
+const watchDebounce = document.getElementById('watch-debounce');
+const watchIgnore = document.getElementById('watch-ignore');
+const watchList = document.getElementById('watch-list');
+
+function renderWatches(watches) {
+    if (!watches || watches.length === 0) {
+        watchList.innerHTML = '<div class="task-empty">暂无监视文件夹</div>';
+        return;
+    }
+    watchList.innerHTML = watches.map(w => {
+        const history = (w.history || []).slice(0, 20).map(h =>
+            `<li>${formatDateTime(h.time)} ${h.path}${h.taskId ? ` → 任务 #${h.taskId}` : ''}</li>`
+        ).join('');
+        return `
+        <div class="video-item schedule-item ${w.enabled ? '' : 'disabled'}">
+            <div class="video-info">
+                <span class="video-name">${w.name} (${w.preset})</span>
+                <div class="video-meta">
+                    <span>📁 ${w.folder}</span>
+                    <span>稳定 ${w.settleSeconds}s</span>
+                    ${w.ignore && w.ignore.length ? `<span>忽略: ${w.ignore.join(', ')}</span>` : ''}
+                    ${w.lastError ? `<span class="schedule-error">${w.lastError}</span>` : ''}
+                </div>
+                <details class="watch-history">
+                    <summary>已处理 ${(w.history || []).length} 项</summary>
+                    <ul>${history}</ul>
+                </details>
+            </div>
+            <div class="schedule-actions">
+                <button class="btn btn-small" data-action="toggle" data-id="${w.id}">${w.enabled ? '暂停' : '启用'}</button>
+                <button class="btn btn-small" data-action="clear" data-id="${w.id}">清除记录</button>
+                <button class="btn btn-small" data-action="delete" data-id="${w.id}">删除</button>
+            </div>
+        </div>`;
+    }).join('');
+}
+
+document.getElementById('watch-selectFolderBtn').addEventListener('click', async () => {
+    const folder = await window.go.main.App.SelectSourceFolder();
+    if (folder) watchFolder.value = folder;
+});
+
+document.getElementById('watch-addBtn').addEventListener('click', async () => {
+    if (!watchPreset.value || !watchFolder.value) {
+        alert('请选择预设和文件夹');
+        return;
+    }
+    try {
+        await window.go.main.App.WatchAdd({
+            preset: watchPreset.value,
+            folder: watchFolder.value,
+            enabled: true,
+            settleSeconds: parseInt(watchSettle.value) || 0,
+            debounceSeconds: parseInt(watchDebounce.value) || 0,
+            ignore: watchIgnore.value.split(',').map(p => p.trim()).filter(p => p),
+        });
+    } catch (error) {
+        alert('添加监视失败: ' + (error.message || error));
+    }
+});
+
+watchList.addEventListener('click', async (e) => {
+    const btn = e.target.closest('button[data-action]');
+    if (!btn) return;
+    const id = parseInt(btn.dataset.id);
+    try {
+        switch (btn.dataset.action) {
+            case 'toggle': {
+                const watches = await window.go.main.App.WatchList();
+                const w = watches.find(x => x.id === id);
+                if (!w) return;
+                w.enabled = !w.enabled;
+                await window.go.main.App.WatchUpdate(id, w);
+                break;
+            }
+            case 'clear':
+                await window.go.main.App.WatchClearHistory(id);
+                break;
+            case 'delete':
+                if (!confirm('确定不再监视这个文件夹吗？')) return;
+                await window.go.main.App.WatchDelete(id);
+                break;
+        }
+    } catch (error) {
+        alert('操作失败: ' + (error.message || error));
+    }
+});
+
+window.runtime.EventsOn('watch-list-update', renderWatches);
+loadSchedules();
+loadSchedulePresets();
+window.go.main.App.WatchList().then(renderWatches);
+
+// ============ 历史记录 ============
+
+const historyList = document.getElementById('history-list');
+const historyCount = document.getElementById('history-count');
+const historyLimit = 200;
+
+function historyQuery() {
+    const from = document.getElementById('history-from').value;
+    const to = document.getElementById('history-to').value;
+    return {
+        from: from ? new Date(from + 'T00:00:00').getTime() : 0,
+        to: to ? new Date(to + 'T23:59:59.999').getTime() : 0,
+        type: document.getElementById('history-type').value,
+        status: document.getElementById('history-status').value,
+        file: document.getElementById('history-file').value.trim(),
+        offset: 0,
+        limit: historyLimit,
+    };
+}
+
+function formatDuration(ms) {
+    if (!ms) return '-';
+    const s = Math.round(ms / 1000);
+    if (s < 60) return `${s}秒`;
+    if (s < 3600) return `${Math.floor(s / 60)}分${s % 60}秒`;
+    return `${Math.floor(s / 3600)}小时${Math.floor(s % 3600 / 60)}分`;
+}
+
+async function searchHistory() {
+    const page = await window.go.main.App.HistoryQuery(historyQuery());
+    historyCount.textContent = page.total > page.records.length
+        ? `共 ${page.total} 条记录 (显示最近 ${page.records.length} 条)`
+        : `共 ${page.total} 条记录`;
+    if (page.records.length === 0) {
+        historyList.innerHTML = '<div class="task-empty">暂无记录</div>';
+        return;
+    }
+    historyList.innerHTML = page.records.map(r => `
+        <div class="video-item">
+            <div class="video-info">
+                <span class="video-name">#${r.taskId} ${r.name || formatTaskType(r.type)}</span>
+                <div class="video-meta">
+                    <span>${formatTaskType(r.type)}</span>
+                    <span>${formatTaskStatus(r.status).icon} ${formatTaskStatus(r.status).text}</span>
+                    <span>${formatDateTime(r.finishedAt)}</span>
+                    <span>耗时 ${formatDuration(r.duration)}</span>
+                    <span>成功 ${r.success} / 失败 ${r.failed}</span>
+                </div>
+                ${r.error ? `<div class="video-meta"><span class="schedule-error">${r.error}</span></div>` : ''}
+                ${(r.errors || []).length ? `<details class="watch-history"><summary>错误 ${r.errors.length} 项</summary><ul>${
+                    r.errors.map(e => `<li>${e.file || e.gallery || e.path}: ${e.error}</li>`).join('')
+                }</ul></details>` : ''}
+            </div>
+        </div>
+    `).join('');
+}
+
+async function exportHistory(format) {
+    try {
+        const n = await window.go.main.App.HistoryExportDialog(historyQuery(), format);
+        if (n) alert(`已导出 ${n} 条记录`);
+    } catch (error) {
+        alert('导出失败: ' + (error.message || error));
+    }
+}
+
+document.getElementById('history-searchBtn').addEventListener('click', searchHistory);
+document.getElementById('history-exportCsvBtn').addEventListener('click', () => exportHistory('csv'));
+document.getElementById('history-exportJsonBtn').addEventListener('click', () => exportHistory('json'));
+document.querySelector('.tab-btn[data-tab="history"]').addEventListener('click', searchHistory);
 
 // ============ TXT转EPUB工具 ============
 
@@ -1144,6 +1740,11 @@ txt2epubStartBtn.addEventListener('click', async () => {
                 customPattern: customPattern
             }
         });
+        saveToolSettings('SettingsSetTxt2Epub', {
+            outputPath: txt2epubTargetPath.value,
+            author: txt2epubAuthor.value.trim(),
+            customPattern: txt2epubCustomPattern.value.trim()
+        });
 
         // 显示结果
         txt2epubProgressSection.style.display = 'none';
@@ -1558,6 +2159,10 @@ galleryStartBtn.addEventListener('click', async () => {
 
     try {
         const result = await window.go.main.App.GalleryCrawlAndPack(selectedGalleries, galleryOutputPath.value);
+        saveToolSettings('SettingsSetGallery', {
+            outputPath: galleryOutputPath.value,
+            maxPages: parseInt(galleryMaxPages.value, 10)
+        });
 
         // 显示结果
         galleryProgressSection.style.display = 'none';
@@ -1638,3 +2243,50 @@ galleryResetBtn.addEventListener('click', () => {
     galleryStartBtn.disabled = true;
     galleryStartBtn.textContent = '🚀 开始抓取';
 });
+
+// ============ 设置 ============
+
+// 设置中的值只有在下拉框里存在对应选项时才使用
+function setSelectValue(select, value) {
+    if (Array.from(select.options).some(opt => opt.value === String(value))) {
+        select.value = String(value);
+    }
+}
+
+// 用上次保存的设置填充各工具的默认值
+async function applyToolSettings() {
+    let settings;
+    try {
+        settings = await window.go.main.App.SettingsGet();
+    } catch (error) {
+        return;
+    }
+
+    targetPath.value = settings.shortcuts.targetPath || '';
+    const namingRadio = document.querySelector(`input[name="namingMode"][value="${settings.shortcuts.namingMode}"]`);
+    if (namingRadio) namingRadio.checked = true;
+
+    convertVideoPath.value = settings.convert7z.videoOutputPath || '';
+    convertKeepOriginal.checked = settings.convert7z.keepOriginal;
+    setSelectValue(document.getElementById('convert-compressionLevel'), settings.convert7z.compressionLevel);
+    loadPasswordList();
+
+    imagezipTargetPath.value = settings.packImages.targetPath || '';
+    setSelectValue(imagezipCompressionLevel, settings.packImages.compressionLevel);
+
+    txt2epubTargetPath.value = settings.txt2epub.outputPath || '';
+    txt2epubAuthor.value = settings.txt2epub.author || '';
+    txt2epubCustomPattern.value = settings.txt2epub.customPattern || '';
+
+    galleryOutputPath.value = settings.gallery.outputPath || '';
+    setSelectValue(galleryMaxPages, settings.gallery.maxPages);
+}
+
+// 记住本次使用的选项，失败不影响任务本身
+function saveToolSettings(method, values) {
+    window.go.main.App[method](values).catch(error => {
+        console.warn('保存设置失败:', error);
+    });
+}
+
+applyToolSettings();
@@ -1,12 +1,20 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
+import {gallery} from '../models';
+import {scanner} from '../models';
+
+export function ApiServerStart(arg1:string,arg2:string):Promise<main.ApiServerInfo>;
+
+export function ApiServerStatus():Promise<main.ApiServerInfo>;
+
+export function ApiServerStop():Promise<void>;
 
 export function ConvertTxtToEpub(arg1:main.ConvertTxtParams):Promise<main.ConvertResult>;
 
 export function GalleryCancelCrawl():Promise<void>;
 
-export function GalleryCrawlAndPack(arg1:Array<main.Gallery>,arg2:string):Promise<main.CrawlResult>;
+export function GalleryCrawlAndPack(arg1:Array<gallery.Gallery>,arg2:string):Promise<main.CrawlResult>;
 
 export function GallerySearch(arg1:string,arg2:number):Promise<main.GallerySearchResult>;
 
@@ -16,28 +24,136 @@ export function GallerySelectOutputFolder():Promise<string>;
 
 export function GetPlatform():Promise<string>;
 
+export function HistoryExport(arg1:main.HistoryQuery,arg2:string,arg3:string):Promise<number>;
+
+export function HistoryExportDialog(arg1:main.HistoryQuery,arg2:string):Promise<number>;
+
+export function HistoryQuery(arg1:main.HistoryQuery):Promise<main.HistoryPage>;
+
 export function OpenFolder(arg1:string):Promise<void>;
 
+export function PasswordAdd(arg1:string):Promise<void>;
+
+export function PasswordList():Promise<Array<string>>;
+
+export function PasswordListSet(arg1:Array<string>):Promise<Array<string>>;
+
+export function PresetDelete(arg1:string):Promise<void>;
+
+export function PresetEnqueue(arg1:string,arg2:string):Promise<number>;
+
+export function PresetGet(arg1:string):Promise<main.Preset>;
+
+export function PresetList():Promise<Array<main.Preset>>;
+
+export function PresetSave(arg1:main.Preset):Promise<main.Preset>;
+
+export function PresetSaveFromTask(arg1:number,arg2:string):Promise<main.Preset>;
+
+export function PresetUpdate(arg1:string,arg2:main.Preset):Promise<main.Preset>;
+
 export function PreviewTxtChapters(arg1:main.PreviewTxtParams):Promise<main.PreviewResult>;
 
-export function Scan7zFiles(arg1:string):Promise<Array<main.FileInfo>>;
+export function ScanArchives(arg1:string):Promise<Array<scanner.FileInfo>>;
+
+export function ScanImageFolders(arg1:string):Promise<Array<scanner.FolderInfo>>;
+
+export function ScanTxtFiles(arg1:string):Promise<Array<scanner.FileInfo>>;
+
+export function ScanVideos(arg1:string):Promise<Array<scanner.VideoFile>>;
+
+export function ScheduleAdd(arg1:main.Schedule):Promise<main.Schedule>;
+
+export function ScheduleDelete(arg1:number):Promise<void>;
+
+export function ScheduleForget(arg1:number):Promise<void>;
 
-export function ScanImageFolders(arg1:string):Promise<Array<main.FolderInfo>>;
+export function ScheduleList():Promise<Array<main.Schedule>>;
 
-export function ScanTxtFiles(arg1:string):Promise<Array<main.FileInfo>>;
+export function ScheduleRunNow(arg1:number):Promise<number>;
 
-export function ScanVideos(arg1:string):Promise<Array<main.VideoFile>>;
+export function ScheduleUpdate(arg1:number,arg2:main.Schedule):Promise<main.Schedule>;
 
 export function SelectSourceFolder():Promise<string>;
 
 export function SelectTargetFolder():Promise<string>;
 
-export function SelectTxtFile():Promise<main.FileInfo>;
+export function SelectTxtFile():Promise<scanner.FileInfo>;
+
+export function SettingsAddRecentFolder(arg1:string,arg2:string):Promise<void>;
+
+export function SettingsGet():Promise<main.Settings>;
+
+export function SettingsGetConvert7z():Promise<main.Convert7zSettings>;
+
+export function SettingsGetGallery():Promise<main.GallerySettings>;
+
+export function SettingsGetPackImages():Promise<main.PackImagesSettings>;
+
+export function SettingsGetRecentFolders(arg1:string):Promise<Array<string>>;
+
+export function SettingsGetShortcuts():Promise<main.ShortcutsSettings>;
+
+export function SettingsGetTxt2Epub():Promise<main.Txt2EpubSettings>;
+
+export function SettingsReset():Promise<main.Settings>;
+
+export function SettingsSetConvert7z(arg1:main.Convert7zSettings):Promise<void>;
+
+export function SettingsSetGallery(arg1:main.GallerySettings):Promise<void>;
+
+export function SettingsSetPackImages(arg1:main.PackImagesSettings):Promise<void>;
+
+export function SettingsSetShortcuts(arg1:main.ShortcutsSettings):Promise<void>;
+
+export function SettingsSetTxt2Epub(arg1:main.Txt2EpubSettings):Promise<void>;
 
 export function TaskQueueAdd(arg1:string,arg2:any,arg3:string):Promise<number>;
 
+export function TaskQueueAddAfter(arg1:string,arg2:any,arg3:string,arg4:Array<number>):Promise<number>;
+
+export function TaskQueueAddPipeline(arg1:Array<main.PipelineStep>):Promise<Array<number>>;
+
 export function TaskQueueCancel(arg1:number):Promise<void>;
 
 export function TaskQueueClearCompleted():Promise<void>;
 
 export function TaskQueueGetAll():Promise<Array<main.Task>>;
+
+export function TaskQueueGetConcurrency():Promise<Record<string, number>>;
+
+export function TaskQueueGetLogs(arg1:number,arg2:number):Promise<Array<main.TaskLogEntry>>;
+
+export function TaskQueueIsPaused():Promise<boolean>;
+
+export function TaskQueueMove(arg1:number,arg2:number):Promise<void>;
+
+export function TaskQueueMoveToBack(arg1:number):Promise<void>;
+
+export function TaskQueueMoveToFront(arg1:number):Promise<void>;
+
+export function TaskQueuePause(arg1:number):Promise<void>;
+
+export function TaskQueuePauseAll():Promise<void>;
+
+export function TaskQueueResume(arg1:number):Promise<void>;
+
+export function TaskQueueResumeAll():Promise<void>;
+
+export function TaskQueueRetryFailed(arg1:number):Promise<number>;
+
+export function TaskQueueRetryWithPassword(arg1:number,arg2:string,arg3:boolean):Promise<number>;
+
+export function TaskQueueSetConcurrency(arg1:string,arg2:number):Promise<void>;
+
+export function TaskQueueSetPriority(arg1:number,arg2:number):Promise<void>;
+
+export function WatchAdd(arg1:main.Watch):Promise<main.Watch>;
+
+export function WatchClearHistory(arg1:number):Promise<void>;
+
+export function WatchDelete(arg1:number):Promise<void>;
+
+export function WatchList():Promise<Array<main.Watch>>;
+
+export function WatchUpdate(arg1:number,arg2:main.Watch):Promise<main.Watch>;
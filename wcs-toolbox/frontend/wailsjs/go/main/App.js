@@ -2,6 +2,18 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ApiServerStart(arg1, arg2) {
+  return window['go']['main']['App']['ApiServerStart'](arg1, arg2);
+}
+
+export function ApiServerStatus() {
+  return window['go']['main']['App']['ApiServerStatus']();
+}
+
+export function ApiServerStop() {
+  return window['go']['main']['App']['ApiServerStop']();
+}
+
 export function ConvertTxtToEpub(arg1) {
   return window['go']['main']['App']['ConvertTxtToEpub'](arg1);
 }
@@ -30,16 +42,68 @@ export function GetPlatform() {
   return window['go']['main']['App']['GetPlatform']();
 }
 
+export function HistoryExport(arg1, arg2, arg3) {
+  return window['go']['main']['App']['HistoryExport'](arg1, arg2, arg3);
+}
+
+export function HistoryExportDialog(arg1, arg2) {
+  return window['go']['main']['App']['HistoryExportDialog'](arg1, arg2);
+}
+
+export function HistoryQuery(arg1) {
+  return window['go']['main']['App']['HistoryQuery'](arg1);
+}
+
 export function OpenFolder(arg1) {
   return window['go']['main']['App']['OpenFolder'](arg1);
 }
 
+export function PasswordAdd(arg1) {
+  return window['go']['main']['App']['PasswordAdd'](arg1);
+}
+
+export function PasswordList() {
+  return window['go']['main']['App']['PasswordList']();
+}
+
+export function PasswordListSet(arg1) {
+  return window['go']['main']['App']['PasswordListSet'](arg1);
+}
+
+export function PresetDelete(arg1) {
+  return window['go']['main']['App']['PresetDelete'](arg1);
+}
+
+export function PresetEnqueue(arg1, arg2) {
+  return window['go']['main']['App']['PresetEnqueue'](arg1, arg2);
+}
+
+export function PresetGet(arg1) {
+  return window['go']['main']['App']['PresetGet'](arg1);
+}
+
+export function PresetList() {
+  return window['go']['main']['App']['PresetList']();
+}
+
+export function PresetSave(arg1) {
+  return window['go']['main']['App']['PresetSave'](arg1);
+}
+
+export function PresetSaveFromTask(arg1, arg2) {
+  return window['go']['main']['App']['PresetSaveFromTask'](arg1, arg2);
+}
+
+export function PresetUpdate(arg1, arg2) {
+  return window['go']['main']['App']['PresetUpdate'](arg1, arg2);
+}
+
 export function PreviewTxtChapters(arg1) {
   return window['go']['main']['App']['PreviewTxtChapters'](arg1);
 }
 
-export function Scan7zFiles(arg1) {
-  return window['go']['main']['App']['Scan7zFiles'](arg1);
+export function ScanArchives(arg1) {
+  return window['go']['main']['App']['ScanArchives'](arg1);
 }
 
 export function ScanImageFolders(arg1) {
@@ -54,6 +118,30 @@ export function ScanVideos(arg1) {
   return window['go']['main']['App']['ScanVideos'](arg1);
 }
 
+export function ScheduleAdd(arg1) {
+  return window['go']['main']['App']['ScheduleAdd'](arg1);
+}
+
+export function ScheduleDelete(arg1) {
+  return window['go']['main']['App']['ScheduleDelete'](arg1);
+}
+
+export function ScheduleForget(arg1) {
+  return window['go']['main']['App']['ScheduleForget'](arg1);
+}
+
+export function ScheduleList() {
+  return window['go']['main']['App']['ScheduleList']();
+}
+
+export function ScheduleRunNow(arg1) {
+  return window['go']['main']['App']['ScheduleRunNow'](arg1);
+}
+
+export function ScheduleUpdate(arg1, arg2) {
+  return window['go']['main']['App']['ScheduleUpdate'](arg1, arg2);
+}
+
 export function SelectSourceFolder() {
   return window['go']['main']['App']['SelectSourceFolder']();
 }
@@ -66,10 +154,74 @@ export function SelectTxtFile() {
   return window['go']['main']['App']['SelectTxtFile']();
 }
 
+export function SettingsAddRecentFolder(arg1, arg2) {
+  return window['go']['main']['App']['SettingsAddRecentFolder'](arg1, arg2);
+}
+
+export function SettingsGet() {
+  return window['go']['main']['App']['SettingsGet']();
+}
+
+export function SettingsGetConvert7z() {
+  return window['go']['main']['App']['SettingsGetConvert7z']();
+}
+
+export function SettingsGetGallery() {
+  return window['go']['main']['App']['SettingsGetGallery']();
+}
+
+export function SettingsGetPackImages() {
+  return window['go']['main']['App']['SettingsGetPackImages']();
+}
+
+export function SettingsGetRecentFolders(arg1) {
+  return window['go']['main']['App']['SettingsGetRecentFolders'](arg1);
+}
+
+export function SettingsGetShortcuts() {
+  return window['go']['main']['App']['SettingsGetShortcuts']();
+}
+
+export function SettingsGetTxt2Epub() {
+  return window['go']['main']['App']['SettingsGetTxt2Epub']();
+}
+
+export function SettingsReset() {
+  return window['go']['main']['App']['SettingsReset']();
+}
+
+export function SettingsSetConvert7z(arg1) {
+  return window['go']['main']['App']['SettingsSetConvert7z'](arg1);
+}
+
+export function SettingsSetGallery(arg1) {
+  return window['go']['main']['App']['SettingsSetGallery'](arg1);
+}
+
+export function SettingsSetPackImages(arg1) {
+  return window['go']['main']['App']['SettingsSetPackImages'](arg1);
+}
+
+export function SettingsSetShortcuts(arg1) {
+  return window['go']['main']['App']['SettingsSetShortcuts'](arg1);
+}
+
+export function SettingsSetTxt2Epub(arg1) {
+  return window['go']['main']['App']['SettingsSetTxt2Epub'](arg1);
+}
+
 export function TaskQueueAdd(arg1, arg2, arg3) {
   return window['go']['main']['App']['TaskQueueAdd'](arg1, arg2, arg3);
 }
 
+export function TaskQueueAddAfter(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['TaskQueueAddAfter'](arg1, arg2, arg3, arg4);
+}
+
+export function TaskQueueAddPipeline(arg1) {
+  return window['go']['main']['App']['TaskQueueAddPipeline'](arg1);
+}
+
 export function TaskQueueCancel(arg1) {
   return window['go']['main']['App']['TaskQueueCancel'](arg1);
 }
@@ -81,3 +233,79 @@ export function TaskQueueClearCompleted() {
 export function TaskQueueGetAll() {
   return window['go']['main']['App']['TaskQueueGetAll']();
 }
+
+export function TaskQueueGetConcurrency() {
+  return window['go']['main']['App']['TaskQueueGetConcurrency']();
+}
+
+export function TaskQueueGetLogs(arg1, arg2) {
+  return window['go']['main']['App']['TaskQueueGetLogs'](arg1, arg2);
+}
+
+export function TaskQueueIsPaused() {
+  return window['go']['main']['App']['TaskQueueIsPaused']();
+}
+
+export function TaskQueueMove(arg1, arg2) {
+  return window['go']['main']['App']['TaskQueueMove'](arg1, arg2);
+}
+
+export function TaskQueueMoveToBack(arg1) {
+  return window['go']['main']['App']['TaskQueueMoveToBack'](arg1);
+}
+
+export function TaskQueueMoveToFront(arg1) {
+  return window['go']['main']['App']['TaskQueueMoveToFront'](arg1);
+}
+
+export function TaskQueuePause(arg1) {
+  return window['go']['main']['App']['TaskQueuePause'](arg1);
+}
+
+export function TaskQueuePauseAll() {
+  return window['go']['main']['App']['TaskQueuePauseAll']();
+}
+
+export function TaskQueueResume(arg1) {
+  return window['go']['main']['App']['TaskQueueResume'](arg1);
+}
+
+export function TaskQueueResumeAll() {
+  return window['go']['main']['App']['TaskQueueResumeAll']();
+}
+
+export function TaskQueueRetryFailed(arg1) {
+  return window['go']['main']['App']['TaskQueueRetryFailed'](arg1);
+}
+
+export function TaskQueueRetryWithPassword(arg1, arg2, arg3) {
+  return window['go']['main']['App']['TaskQueueRetryWithPassword'](arg1, arg2, arg3);
+}
+
+export function TaskQueueSetConcurrency(arg1, arg2) {
+  return window['go']['main']['App']['TaskQueueSetConcurrency'](arg1, arg2);
+}
+
+export function TaskQueueSetPriority(arg1, arg2) {
+  return window['go']['main']['App']['TaskQueueSetPriority'](arg1, arg2);
+}
+
+export function WatchAdd(arg1) {
+  return window['go']['main']['App']['WatchAdd'](arg1);
+}
+
+export function WatchClearHistory(arg1) {
+  return window['go']['main']['App']['WatchClearHistory'](arg1);
+}
+
+export function WatchDelete(arg1) {
+  return window['go']['main']['App']['WatchDelete'](arg1);
+}
+
+export function WatchList() {
+  return window['go']['main']['App']['WatchList']();
+}
+
+export function WatchUpdate(arg1, arg2) {
+  return window['go']['main']['App']['WatchUpdate'](arg1, arg2);
+}
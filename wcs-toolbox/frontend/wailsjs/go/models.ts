@@ -1,5 +1,44 @@
+export namespace gallery {
+	
+	export class Gallery {
+	    url: string;
+	    title: string;
+	    imageCount: number;
+	    thumbnail: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Gallery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.title = source["title"];
+	        this.imageCount = source["imageCount"];
+	        this.thumbnail = source["thumbnail"];
+	    }
+	}
+
+}
+
 export namespace main {
 	
+	export class ApiServerInfo {
+	    running: boolean;
+	    addr?: string;
+	    token?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ApiServerInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.addr = source["addr"];
+	        this.token = source["token"];
+	    }
+	}
 	export class Chapter {
 	    index: number;
 	    title: string;
@@ -18,10 +57,28 @@ export namespace main {
 	        this.preview = source["preview"];
 	    }
 	}
+	export class Convert7zSettings {
+	    videoOutputPath: string;
+	    keepOriginal: boolean;
+	    compressionLevel: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Convert7zSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoOutputPath = source["videoOutputPath"];
+	        this.keepOriginal = source["keepOriginal"];
+	        this.compressionLevel = source["compressionLevel"];
+	    }
+	}
 	export class ErrorDetail {
 	    file?: string;
 	    gallery?: string;
+	    path?: string;
 	    error: string;
+	    needsPassword?: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new ErrorDetail(source);
@@ -31,7 +88,9 @@ export namespace main {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.file = source["file"];
 	        this.gallery = source["gallery"];
+	        this.path = source["path"];
 	        this.error = source["error"];
+	        this.needsPassword = source["needsPassword"];
 	    }
 	}
 	export class ConvertResult {
@@ -68,24 +127,8 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class FileInfo {
-	    name: string;
-	    path: string;
-	    size: number;
-	
-	    static createFrom(source: any = {}) {
-	        return new FileInfo(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
-	        this.path = source["path"];
-	        this.size = source["size"];
-	    }
-	}
 	export class ConvertTxtParams {
-	    files: FileInfo[];
+	    files: scanner.FileInfo[];
 	    outputPath: string;
 	    // Go type: struct { Author string "json:\"author\""; CustomPattern string "json:\"customPattern\"" }
 	    options: any;
@@ -96,7 +139,7 @@ export namespace main {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.files = this.convertValues(source["files"], FileInfo);
+	        this.files = this.convertValues(source["files"], scanner.FileInfo);
 	        this.outputPath = source["outputPath"];
 	        this.options = this.convertValues(source["options"], Object);
 	    }
@@ -156,65 +199,132 @@ export namespace main {
 		}
 	}
 	
-	
-	export class FolderInfo {
-	    name: string;
-	    path: string;
-	    imageCount: number;
-	    totalSize: number;
+	export class GallerySearchResult {
+	    success: boolean;
+	    error?: string;
+	    galleries: gallery.Gallery[];
+	    currentPage: number;
+	    hasNextPage: boolean;
+	    hasMore: boolean;
+	    pagesLoaded: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new FolderInfo(source);
+	        return new GallerySearchResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
-	        this.path = source["path"];
-	        this.imageCount = source["imageCount"];
-	        this.totalSize = source["totalSize"];
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.galleries = this.convertValues(source["galleries"], gallery.Gallery);
+	        this.currentPage = source["currentPage"];
+	        this.hasNextPage = source["hasNextPage"];
+	        this.hasMore = source["hasMore"];
+	        this.pagesLoaded = source["pagesLoaded"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class Gallery {
-	    url: string;
-	    title: string;
-	    imageCount: number;
-	    thumbnail: string;
+	export class GallerySettings {
+	    outputPath: string;
+	    maxPages: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Gallery(source);
+	        return new GallerySettings(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.url = source["url"];
-	        this.title = source["title"];
-	        this.imageCount = source["imageCount"];
-	        this.thumbnail = source["thumbnail"];
+	        this.outputPath = source["outputPath"];
+	        this.maxPages = source["maxPages"];
 	    }
 	}
-	export class GallerySearchResult {
-	    success: boolean;
+	export class HistoryRecord {
+	    taskId: number;
+	    type: string;
+	    name: string;
+	    status: string;
+	    data?: any;
+	    items?: string[];
+	    outputs?: string[];
+	    createdAt: number;
+	    startedAt?: number;
+	    finishedAt: number;
+	    duration: number;
+	    success: number;
+	    failed: number;
 	    error?: string;
-	    galleries: Gallery[];
-	    currentPage: number;
-	    hasNextPage: boolean;
-	    hasMore: boolean;
-	    pagesLoaded: number;
+	    errors?: ErrorDetail[];
 	
 	    static createFrom(source: any = {}) {
-	        return new GallerySearchResult(source);
+	        return new HistoryRecord(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.taskId = source["taskId"];
+	        this.type = source["type"];
+	        this.name = source["name"];
+	        this.status = source["status"];
+	        this.data = source["data"];
+	        this.items = source["items"];
+	        this.outputs = source["outputs"];
+	        this.createdAt = source["createdAt"];
+	        this.startedAt = source["startedAt"];
+	        this.finishedAt = source["finishedAt"];
+	        this.duration = source["duration"];
 	        this.success = source["success"];
+	        this.failed = source["failed"];
 	        this.error = source["error"];
-	        this.galleries = this.convertValues(source["galleries"], Gallery);
-	        this.currentPage = source["currentPage"];
-	        this.hasNextPage = source["hasNextPage"];
-	        this.hasMore = source["hasMore"];
-	        this.pagesLoaded = source["pagesLoaded"];
+	        this.errors = this.convertValues(source["errors"], ErrorDetail);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HistoryPage {
+	    records: HistoryRecord[];
+	    total: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.records = this.convertValues(source["records"], HistoryRecord);
+	        this.total = source["total"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -235,6 +345,81 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class HistoryQuery {
+	    from: number;
+	    to: number;
+	    type: string;
+	    status: string;
+	    file: string;
+	    offset: number;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.type = source["type"];
+	        this.status = source["status"];
+	        this.file = source["file"];
+	        this.offset = source["offset"];
+	        this.limit = source["limit"];
+	    }
+	}
+	
+	export class PackImagesSettings {
+	    targetPath: string;
+	    compressionLevel: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PackImagesSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.targetPath = source["targetPath"];
+	        this.compressionLevel = source["compressionLevel"];
+	    }
+	}
+	export class PipelineStep {
+	    type: string;
+	    name: string;
+	    data: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new PipelineStep(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.name = source["name"];
+	        this.data = source["data"];
+	    }
+	}
+	export class Preset {
+	    name: string;
+	    type: string;
+	    data: any;
+	    createdAt: number;
+	    updatedAt: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Preset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.data = source["data"];
+	        this.createdAt = source["createdAt"];
+	        this.updatedAt = source["updatedAt"];
+	    }
+	}
 	export class PreviewResult {
 	    success: boolean;
 	    error?: string;
@@ -285,6 +470,121 @@ export namespace main {
 	        this.customPattern = source["customPattern"];
 	    }
 	}
+	export class Schedule {
+	    id: number;
+	    name: string;
+	    preset?: string;
+	    type?: string;
+	    data?: any;
+	    folder: string;
+	    cron?: string;
+	    runAt?: number;
+	    enabled: boolean;
+	    nextRun?: number;
+	    lastRun?: number;
+	    lastTaskId?: number;
+	    lastError?: string;
+	    seen?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Schedule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.preset = source["preset"];
+	        this.type = source["type"];
+	        this.data = source["data"];
+	        this.folder = source["folder"];
+	        this.cron = source["cron"];
+	        this.runAt = source["runAt"];
+	        this.enabled = source["enabled"];
+	        this.nextRun = source["nextRun"];
+	        this.lastRun = source["lastRun"];
+	        this.lastTaskId = source["lastTaskId"];
+	        this.lastError = source["lastError"];
+	        this.seen = source["seen"];
+	    }
+	}
+	export class Txt2EpubSettings {
+	    outputPath: string;
+	    author: string;
+	    customPattern: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Txt2EpubSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.outputPath = source["outputPath"];
+	        this.author = source["author"];
+	        this.customPattern = source["customPattern"];
+	    }
+	}
+	export class ShortcutsSettings {
+	    targetPath: string;
+	    namingMode: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShortcutsSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.targetPath = source["targetPath"];
+	        this.namingMode = source["namingMode"];
+	    }
+	}
+	export class Settings {
+	    version: number;
+	    shortcuts: ShortcutsSettings;
+	    convert7z: Convert7zSettings;
+	    packImages: PackImagesSettings;
+	    txt2epub: Txt2EpubSettings;
+	    gallery: GallerySettings;
+	    concurrency: Record<string, number>;
+	    recentSources: string[];
+	    recentTargets: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.shortcuts = this.convertValues(source["shortcuts"], ShortcutsSettings);
+	        this.convert7z = this.convertValues(source["convert7z"], Convert7zSettings);
+	        this.packImages = this.convertValues(source["packImages"], PackImagesSettings);
+	        this.txt2epub = this.convertValues(source["txt2epub"], Txt2EpubSettings);
+	        this.gallery = this.convertValues(source["gallery"], GallerySettings);
+	        this.concurrency = source["concurrency"];
+	        this.recentSources = source["recentSources"];
+	        this.recentTargets = source["recentTargets"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
 	export class Task {
 	    id: number;
 	    type: string;
@@ -292,9 +592,23 @@ export namespace main {
 	    status: string;
 	    data: any;
 	    progress: number;
+	    bytesDone?: number;
+	    bytesTotal?: number;
+	    throughput?: number;
+	    eta?: number;
 	    result?: any;
 	    error?: string;
+	    diagnostic?: string;
 	    createdAt: number;
+	    startedAt?: number;
+	    finishedAt?: number;
+	    completed?: string[];
+	    parentId?: number;
+	    priority: number;
+	    order: number;
+	    dependsOn?: number[];
+	    feedFrom?: number;
+	    outputs?: string[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Task(source);
@@ -308,9 +622,147 @@ export namespace main {
 	        this.status = source["status"];
 	        this.data = source["data"];
 	        this.progress = source["progress"];
+	        this.bytesDone = source["bytesDone"];
+	        this.bytesTotal = source["bytesTotal"];
+	        this.throughput = source["throughput"];
+	        this.eta = source["eta"];
 	        this.result = source["result"];
 	        this.error = source["error"];
+	        this.diagnostic = source["diagnostic"];
 	        this.createdAt = source["createdAt"];
+	        this.startedAt = source["startedAt"];
+	        this.finishedAt = source["finishedAt"];
+	        this.completed = source["completed"];
+	        this.parentId = source["parentId"];
+	        this.priority = source["priority"];
+	        this.order = source["order"];
+	        this.dependsOn = source["dependsOn"];
+	        this.feedFrom = source["feedFrom"];
+	        this.outputs = source["outputs"];
+	    }
+	}
+	export class TaskLogEntry {
+	    seq: number;
+	    time: number;
+	    level: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TaskLogEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.time = source["time"];
+	        this.level = source["level"];
+	        this.message = source["message"];
+	    }
+	}
+	
+	export class WatchHistoryEntry {
+	    path: string;
+	    taskId?: number;
+	    time: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new WatchHistoryEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.taskId = source["taskId"];
+	        this.time = source["time"];
+	    }
+	}
+	export class Watch {
+	    id: number;
+	    name: string;
+	    folder: string;
+	    preset: string;
+	    enabled: boolean;
+	    settleSeconds: number;
+	    debounceSeconds: number;
+	    ignore: string[];
+	    lastError?: string;
+	    history: WatchHistoryEntry[];
+	    processed?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Watch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.folder = source["folder"];
+	        this.preset = source["preset"];
+	        this.enabled = source["enabled"];
+	        this.settleSeconds = source["settleSeconds"];
+	        this.debounceSeconds = source["debounceSeconds"];
+	        this.ignore = source["ignore"];
+	        this.lastError = source["lastError"];
+	        this.history = this.convertValues(source["history"], WatchHistoryEntry);
+	        this.processed = source["processed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace scanner {
+	
+	export class FileInfo {
+	    name: string;
+	    path: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.size = source["size"];
+	    }
+	}
+	export class FolderInfo {
+	    name: string;
+	    path: string;
+	    imageCount: number;
+	    totalSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FolderInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.imageCount = source["imageCount"];
+	        this.totalSize = source["totalSize"];
 	    }
 	}
 	export class VideoFile {
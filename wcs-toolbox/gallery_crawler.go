@@ -1,125 +1,26 @@
 package main
 
 import (
-	"archive/zip"
 	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
-	"regexp"
-	"strconv"
-	"strings"
-	"sync"
-	"time"
 
-	"github.com/PuerkitoBio/goquery"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"wcs-toolbox/pkg/progress"
 )
 
 // ============ Gallery Crawler ============
 
 func (a *App) GallerySearch(keyword string, page int) GallerySearchResult {
-	if page < 1 {
-		page = 1
-	}
-
-	searchUrl := fmt.Sprintf("https://www.hentaiclub.net/search/%s/", url.QueryEscape(keyword))
-	if page > 1 {
-		searchUrl = fmt.Sprintf("https://www.hentaiclub.net/search/%s/%d/", url.QueryEscape(keyword), page)
-	}
-
-	req, _ := http.NewRequest("GET", searchUrl, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-
-	resp, err := a.crawlerClient.Do(req)
-	if err != nil {
-		return GallerySearchResult{Success: false, Error: err.Error(), CurrentPage: page}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return GallerySearchResult{Success: false, Error: fmt.Sprintf("Status code: %d", resp.StatusCode), CurrentPage: page}
-	}
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	res, err := a.crawler.Search(keyword, page)
 	if err != nil {
-		return GallerySearchResult{Success: false, Error: err.Error(), CurrentPage: page}
-	}
-
-	var galleries []Gallery
-	doc.Find(".item-link, a.item-link, .post-item a").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if !exists {
-			return
-		}
-
-		if (strings.Contains(href, "/r15/") || strings.Contains(href, "/r18/")) && strings.HasSuffix(href, ".html") {
-			title := strings.TrimSpace(s.Find(".item-title, .post-title, h2, h3").Text())
-			if title == "" {
-				title, _ = s.Attr("title")
-			}
-			if title == "" {
-				title = strings.TrimSpace(s.Text())
-			}
-
-			// Extract image count
-			count := 0
-			// Regex \[(\d+)P\]
-			// Simplified extraction
-			if idx := strings.LastIndex(title, "["); idx != -1 {
-				part := title[idx:] // [123P]
-				if idx2 := strings.Index(part, "P]"); idx2 != -1 {
-					cStr := part[1:idx2]
-					count, _ = strconv.Atoi(cStr)
-				}
-			}
-
-			thumb, _ := s.Find("img").Attr("src")
-			if thumb == "" {
-				thumb, _ = s.Find("img").Attr("data-src")
-			}
-
-			if !strings.HasPrefix(href, "http") {
-				href = "https://www.hentaiclub.net" + href
-			}
-
-			galleries = append(galleries, Gallery{
-				URL:        href,
-				Title:      title,
-				ImageCount: count,
-				Thumbnail:  thumb,
-			})
-		}
-	})
-
-	// Deduplicate
-	unique := make([]Gallery, 0, len(galleries))
-	seen := make(map[string]bool)
-	for _, g := range galleries {
-		if !seen[g.URL] {
-			seen[g.URL] = true
-			unique = append(unique, g)
-		}
+		return GallerySearchResult{Success: false, Error: err.Error(), CurrentPage: res.CurrentPage}
 	}
-
-	// Check HasNextPage
-	hasNext := false
-	doc.Find("a[href*='/search/']").Each(func(i int, s *goquery.Selection) {
-		href, _ := s.Attr("href")
-		if strings.Contains(href, fmt.Sprintf("/%d/", page+1)) {
-			hasNext = true
-		}
-	})
-
 	return GallerySearchResult{
 		Success:     true,
-		Galleries:   unique,
-		CurrentPage: page,
-		HasNextPage: hasNext,
-		HasMore:     hasNext,
+		Galleries:   res.Galleries,
+		CurrentPage: res.CurrentPage,
+		HasNextPage: res.HasNextPage,
+		HasMore:     res.HasNextPage,
 	}
 }
 
@@ -132,45 +33,19 @@ func (a *App) GallerySearchAll(keyword string, maxPages int) GallerySearchResult
 	ctx, cancel := context.WithCancel(context.Background())
 	a.crawlerCancel = cancel
 
-	var allGalleries []Gallery
-	seen := make(map[string]bool)
-
-	currentPage := 1
-	hasMore := true
-
-	for currentPage <= maxPages && hasMore {
-		if ctx.Err() != nil {
-			break
-		}
-
-		res := a.GallerySearch(keyword, currentPage)
-		if !res.Success {
-			break
-		}
-
-		for _, g := range res.Galleries {
-			if !seen[g.URL] {
-				seen[g.URL] = true
-				allGalleries = append(allGalleries, g)
-			}
-		}
-
-		runtime.EventsEmit(a.ctx, "gallery-search-progress", map[string]interface{}{
-			"currentPage":    currentPage,
+	galleries, pages, hasMore := a.crawler.SearchAll(ctx, keyword, maxPages, func(page, found int) {
+		a.emitEvent("gallery-search-progress", map[string]interface{}{
+			"currentPage":    page,
 			"maxPages":       maxPages,
-			"galleriesFound": len(allGalleries),
+			"galleriesFound": found,
 		})
-
-		hasMore = res.HasNextPage
-		currentPage++
-		time.Sleep(500 * time.Millisecond) // Delay
-	}
+	})
 
 	return GallerySearchResult{
 		Success:     true,
-		Galleries:   allGalleries,
-		PagesLoaded: currentPage - 1,
-		HasMore:     hasMore && currentPage <= maxPages,
+		Galleries:   galleries,
+		PagesLoaded: pages,
+		HasMore:     hasMore,
 	}
 }
 
@@ -199,17 +74,17 @@ func (a *App) GalleryCrawlAndPack(galleries []Gallery, outputPath string) CrawlR
 			break
 		}
 
-		runtime.EventsEmit(a.ctx, "gallery-crawl-progress", map[string]interface{}{
+		a.emitEvent("gallery-crawl-progress", map[string]interface{}{
 			"current":        i + 1,
 			"total":          len(galleries),
 			"currentGallery": g.Title,
 			"stage":          "fetching",
 		})
 
-		err := a.processGallery(ctx, g, outputPath, i+1, len(galleries))
+		_, err := a.processGallery(ctx, g, outputPath, i+1, len(galleries), nil)
 		if err != nil {
 			result.Failed++
-			result.Errors = append(result.Errors, ErrorDetail{Gallery: g.Title, Error: err.Error()})
+			result.Errors = append(result.Errors, ErrorDetail{Gallery: g.Title, Path: g.URL, Error: err.Error()})
 		} else {
 			result.Success++
 			result.TotalImages += g.ImageCount // Approximation, or actual downloaded count
@@ -219,135 +94,68 @@ func (a *App) GalleryCrawlAndPack(galleries []Gallery, outputPath string) CrawlR
 	return result
 }
 
-func (a *App) processGallery(ctx context.Context, g Gallery, outputPath string, gIdx, gTotal int) error {
-	// Fetch images
-	req, _ := http.NewRequest("GET", g.URL, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0")
-	resp, err := a.crawlerClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	var images []string
-
-	// Selectors
-	doc.Find("#masonry .post-item img, .post-item-img, .post-content img, article img").Each(func(i int, s *goquery.Selection) {
-		src, _ := s.Attr("data-src")
-		if src == "" {
-			src, _ = s.Attr("src")
-		}
-		if src != "" && !strings.Contains(src, "logo") && !strings.Contains(src, "ads") {
-			if !strings.HasPrefix(src, "http") {
-				src = "https:" + src
-			}
-			images = append(images, src)
-		}
-	})
-
-	if len(images) == 0 {
-		return fmt.Errorf("no images found")
-	}
-
-	// Download
-	// Create Zip
-	safeTitle := sanitizeFilename(g.Title)
-	if len(safeTitle) > 100 {
-		safeTitle = safeTitle[:100]
+// handleCrawlGallery is the task-queue variant of GalleryCrawlAndPack
+func (a *App) handleCrawlGallery(ctx context.Context, task *Task) (interface{}, error) {
+	params, ok := task.Data.(*CrawlGalleryParams)
+	if !ok {
+		return nil, fmt.Errorf("invalid data format")
 	}
-	zipPath := filepath.Join(outputPath, safeTitle+".zip")
+	outputPath := params.OutputPath
 
-	// Prepare for zip
-	f, err := os.Create(zipPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+	os.MkdirAll(outputPath, 0755)
 
-	zw := zip.NewWriter(f)
-	defer zw.Close()
+	result := CrawlResult{Errors: []ErrorDetail{}}
+	total := len(params.Galleries)
+	done := a.completedItems(task)
 
-	// Concurrent config
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 5) // max concurrent
-	var downloaded int
-	var mu sync.Mutex
+	// Sizes are unknown up front; each download adds its Content-Length
+	a.setTaskBytesTotal(task, 0)
 
-	for j, imgUrl := range images {
-		if ctx.Err() != nil {
-			return ctx.Err()
+	for i, g := range params.Galleries {
+		if err := a.waitIfPaused(ctx, task); err != nil {
+			return nil, err
 		}
 
-		wg.Add(1)
-		sem <- struct{}{}
-
-		go func(idx int, u string) {
-			defer wg.Done()
-			defer func() { <-sem }()
-
-			// Download
-			data, err := downloadUrl(a.crawlerClient, u)
-			if err == nil {
-				mu.Lock()
-				// Write to zip
-				ext := filepath.Ext(parseUrlPath(u))
-				if ext == "" {
-					ext = ".jpg"
-				} // Default
-				fname := fmt.Sprintf("%04d%s", idx+1, ext)
+		if done[g.URL] {
+			result.Success++
+			continue
+		}
 
-				zipFile, _ := zw.Create(fname)
-				zipFile.Write(data)
+		a.taskLogf(task, "info", "抓取 %s", g.URL)
+		zipPath, err := a.processGallery(ctx, g, outputPath, i+1, total, task)
+		if err != nil {
+			a.taskLogf(task, "error", "抓取失败 %s: %v", g.Title, err)
+			result.Failed++
+			result.Errors = append(result.Errors, ErrorDetail{Gallery: g.Title, Path: g.URL, Error: err.Error()})
+		} else {
+			result.Success++
+			result.TotalImages += g.ImageCount
+			a.markItemDone(task, g.URL)
+			a.addTaskOutput(task, zipPath)
+			a.taskLogf(task, "info", "已生成 %s", zipPath)
+		}
 
-				downloaded++
-				// Notify
-				runtime.EventsEmit(a.ctx, "gallery-crawl-progress", map[string]interface{}{
-					"current":        gIdx,
-					"total":          gTotal,
-					"currentGallery": g.Title,
-					"stage":          "downloading",
-					"downloaded":     downloaded,
-					"totalImages":    len(images), // Note: mismatched field name vs frontend (imageTotal), fixed below
-					"imageTotal":     len(images),
-				})
-				mu.Unlock()
-			}
-		}(j, imgUrl)
+		a.updateTaskProgress(task, i+1, total)
 	}
 
-	wg.Wait()
-
-	if downloaded == 0 {
-		return fmt.Errorf("all downloads failed")
-	}
-	return nil
+	return result, nil
 }
 
-func sanitizeFilename(name string) string {
-	reg := regexp.MustCompile(`[<>:"/\\|?*]`)
-	return reg.ReplaceAllString(name, "_")
-}
-
-func parseUrlPath(u string) string {
-	parsed, _ := url.Parse(u)
-	return parsed.Path
-}
-
-func downloadUrl(client *http.Client, u string) ([]byte, error) {
-	req, _ := http.NewRequest("GET", u, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0")
-	req.Header.Set("Referer", "https://www.hentaiclub.net/")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// processGallery downloads one gallery into a zip. task is nil when called outside the queue.
+func (a *App) processGallery(ctx context.Context, g Gallery, outputPath string, gIdx, gTotal int, task *Task) (string, error) {
+	var rep progress.Reporter = progress.Nop
+	if task != nil {
+		rep = taskReporter{a, task}
 	}
-	defer resp.Body.Close()
-
-	return io.ReadAll(resp.Body)
+	return a.crawler.Download(ctx, g, outputPath, rep, func(downloaded, total int) {
+		a.emitEvent("gallery-crawl-progress", map[string]interface{}{
+			"current":        gIdx,
+			"total":          gTotal,
+			"currentGallery": g.Title,
+			"stage":          "downloading",
+			"downloaded":     downloaded,
+			"totalImages":    total, // Note: mismatched field name vs frontend (imageTotal), fixed below
+			"imageTotal":     total,
+		})
+	})
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,6 +13,11 @@ import (
 var assets embed.FS
 
 func main() {
+	// Subcommands run headless, see cli.go
+	if isCLIInvocation(os.Args[1:]) {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
@@ -25,6 +31,7 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app,
 		},
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"wcs-toolbox/pkg/archive"
+)
+
+// ============ Archive Passwords ============
+
+// The password list lives in passwords.json next to the other stores, in plain
+// text: it only ever leaves this machine if the user copies it. Encrypted
+// archives are tried with each entry in order.
+
+func (a *App) loadPasswords() {
+	a.passwordsMutex.Lock()
+	defer a.passwordsMutex.Unlock()
+
+	a.passwords = nil
+	dir, err := appDataDir()
+	if err != nil {
+		return
+	}
+	a.passwordsPath = filepath.Join(dir, "passwords.json")
+
+	raw, err := os.ReadFile(a.passwordsPath)
+	if err != nil {
+		return
+	}
+	json.Unmarshal(raw, &a.passwords)
+}
+
+// savePasswordsLocked writes the list; caller must hold passwordsMutex
+func (a *App) savePasswordsLocked() error {
+	if a.passwordsPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(a.passwords, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Like writeFileAtomic, but readable by the user only
+	tmp := a.passwordsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.passwordsPath)
+}
+
+// uniquePasswords drops blanks and repeats, keeping the first occurrence
+func uniquePasswords(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, list := range lists {
+		for _, p := range list {
+			if strings.TrimSpace(p) == "" || seen[p] {
+				continue
+			}
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (a *App) PasswordList() []string {
+	a.passwordsMutex.Lock()
+	defer a.passwordsMutex.Unlock()
+	return uniquePasswords(a.passwords)
+}
+
+// PasswordListSet replaces the list; the order is the order they are tried in
+func (a *App) PasswordListSet(list []string) ([]string, error) {
+	a.passwordsMutex.Lock()
+	defer a.passwordsMutex.Unlock()
+	a.passwords = uniquePasswords(list)
+	return a.passwords, a.savePasswordsLocked()
+}
+
+// PasswordAdd appends password to the list unless it is there already
+func (a *App) PasswordAdd(password string) error {
+	a.passwordsMutex.Lock()
+	defer a.passwordsMutex.Unlock()
+	a.passwords = uniquePasswords(a.passwords, []string{password})
+	return a.savePasswordsLocked()
+}
+
+// archivePasswords is what an archive task tries: its own passwords (given on
+// retry) first, then the stored list
+func (a *App) archivePasswords(own []string) []string {
+	return uniquePasswords(own, a.PasswordList())
+}
+
+// maskPassword shows enough of a password to tell the list entries apart in
+// a log without writing it out
+func maskPassword(p string) string {
+	r := []rune(p)
+	if len(r) <= 2 {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[0]) + strings.Repeat("*", len(r)-2) + string(r[len(r)-1])
+}
+
+// needsPassword tells a failed conversion of an encrypted archive, which
+// TaskQueueRetryWithPassword can retry
+func needsPassword(err error) bool {
+	return errors.Is(err, archive.ErrPasswordRequired)
+}
+
+// passwordSource says which password opened an archive, for the task log
+func (a *App) passwordSource(password string, own []string) string {
+	if slices.Contains(own, password) {
+		return fmt.Sprintf("任务指定的密码 %s", maskPassword(password))
+	}
+	if i := slices.Index(a.PasswordList(), password); i >= 0 {
+		return fmt.Sprintf("密码列表第 %d 个密码 %s", i+1, maskPassword(password))
+	}
+	return fmt.Sprintf("密码 %s", maskPassword(password))
+}
@@ -0,0 +1,405 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"wcs-toolbox/pkg/progress"
+	"wcs-toolbox/pkg/scanner"
+)
+
+// Converted describes the output of ConvertToZip
+type Converted struct {
+	Zip      string   `json:"zip,omitempty"` // empty when the archive held only videos
+	Files    int      `json:"files"`         // files packed into Zip
+	Videos   []string `json:"videos,omitempty"`
+	Password string   `json:"-"` // the one that opened an encrypted archive
+
+	videoSizes []int64 // size in the archive of each of Videos
+	videoCount int     // videos in the archive, whether or not they made it to videoOut
+}
+
+// ErrPasswordRequired is wrapped by the error for an encrypted archive that
+// none of the passwords (if any) opened
+var ErrPasswordRequired = errors.New("archive is encrypted, password required")
+
+// ConvertToZip repacks the archive at src (any of the formats above, found by
+// content; for a multi-volume archive the first volume) as a zip next to it.
+// Entries are streamed from the archive straight into the zip, and videos
+// straight into videoOut, so neither a temp dir nor whole files in memory are
+// needed. An encrypted archive is tried with each of passwords in turn. level
+// is the zip's compression level, 0-9 (see zipMethod). rep receives exactly
+// the size of the archive's volumes in bytes over the whole conversion.
+func ConvertToZip(ctx context.Context, src, videoOut string, passwords []string, level int, rep progress.Reporter) (Converted, error) {
+	size := VolumesSize(src)
+	var reported int64
+	defer func() { rep.Add(size - reported) }()
+
+	format, err := Detect(src)
+	if err != nil {
+		return Converted{}, err
+	}
+	out, err := streamToZip(ctx, format, src, "", videoOut, level, size, &reported, rep)
+	var se sourceError
+	if err == nil || ctx.Err() != nil || !errors.As(err, &se) {
+		return out, err
+	}
+	if format.encrypted != nil && format.encrypted(err) {
+		return tryPasswords(ctx, format, src, videoOut, passwords, level, size, &reported, rep)
+	}
+	if !format.external {
+		return out, err
+	}
+
+	// The archive itself is the problem (unsupported method, damaged header...):
+	// let the 7z binary have a go, which needs a temp dir
+	bin := external7z()
+	if bin == "" {
+		return out, fmt.Errorf("%s extract failed: %w", format.Name, err)
+	}
+	rep.Logf("warn", "内置解压失败 (%v)，改用 %s", err, bin)
+	return convertWithTempDir(ctx, bin, src, videoOut, level, size, &reported, rep)
+}
+
+// sourceError marks a failure to read the source archive, as opposed to
+// writing the output
+type sourceError struct{ err error }
+
+func (e sourceError) Error() string { return e.err.Error() }
+func (e sourceError) Unwrap() error { return e.err }
+
+// tryPasswords converts an archive known to be encrypted. Once it is, any read
+// error counts as a wrong password: not every format can tell them apart.
+func tryPasswords(ctx context.Context, format *Format, src, videoOut string, passwords []string, level int, size int64, reported *int64, rep progress.Reporter) (Converted, error) {
+	if len(passwords) == 0 {
+		return Converted{}, ErrPasswordRequired
+	}
+	rep.Logf("info", "%s 已加密，尝试 %d 个密码", filepath.Base(src), len(passwords))
+	for _, password := range passwords {
+		out, err := streamToZip(ctx, format, src, password, videoOut, level, size, reported, rep)
+		if err == nil {
+			out.Password = password
+			return out, nil
+		}
+		var se sourceError
+		if ctx.Err() != nil || !errors.As(err, &se) {
+			return out, err
+		}
+	}
+	return Converted{}, fmt.Errorf("%w: none of %d passwords worked", ErrPasswordRequired, len(passwords))
+}
+
+func streamToZip(ctx context.Context, format *Format, src, password, videoOut string, level int, size int64, reported *int64, rep progress.Reporter) (out Converted, err error) {
+	zipPath := zipPathFor(src)
+	if format.list != nil {
+		entries, err := format.list(src, password)
+		if err != nil {
+			return out, sourceError{err}
+		}
+		var zipTotal, videoTotal int64
+		for _, e := range entries {
+			if e.IsDir {
+				continue
+			}
+			if scanner.IsVideo(e.Name) {
+				videoTotal += e.Size
+				out.videoCount++
+			} else {
+				zipTotal += e.Size
+			}
+		}
+		if err := checkFreeSpace(filepath.Dir(zipPath), zipTotal); err != nil {
+			return out, err
+		}
+		if err := checkFreeSpace(videoOut, videoTotal); err != nil {
+			return out, err
+		}
+	}
+
+	r, err := format.open(src, password)
+	if err != nil {
+		return out, sourceError{err}
+	}
+	defer r.Close()
+
+	// Report the archive's size in proportion to how far the reader got
+	count := progress.Counter{Add: func(int64) {
+		done, total := r.Progress()
+		if share := size * done / max(total, 1); share > *reported {
+			rep.Add(share - *reported)
+			*reported = share
+		}
+	}}
+
+	// The zip is written under a temporary name and only renamed once complete.
+	// On failure the videos written so far go too, so a retry starts clean.
+	partPath := zipPath + ".part"
+	var zf *os.File
+	var zw *zip.Writer
+	defer func() {
+		if zw != nil {
+			if cerr := zw.Close(); err == nil {
+				err = cerr
+			}
+			if cerr := zf.Close(); err == nil {
+				err = cerr
+			}
+			if err == nil {
+				err = os.Rename(partPath, zipPath)
+			}
+			if err != nil {
+				os.Remove(partPath)
+			}
+		}
+		if err != nil {
+			for _, v := range out.Videos {
+				os.Remove(v)
+			}
+			out.Videos = nil
+			return
+		}
+		if zw != nil {
+			out.Zip = zipPath
+		}
+	}()
+
+	buf := make([]byte, copyBufferSize)
+	// Entries are read in archive order so each solid block is decoded only once
+	for {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return out, sourceError{err}
+		}
+		// Cleaned against a root so "../" can't climb out
+		name := path.Clean("/" + strings.ReplaceAll(e.Name, "\\", "/"))[1:]
+		if e.IsDir || name == "" {
+			continue
+		}
+
+		if scanner.IsVideo(name) {
+			if format.list == nil {
+				out.videoCount++
+			}
+			dest := uniquePath(filepath.Join(videoOut, path.Base(name)))
+			n, err := streamEntry(ctx, r, buf, count, func() (io.WriteCloser, error) { return os.Create(dest) })
+			if err != nil {
+				os.Remove(dest)
+				return out, fmt.Errorf("%s: %w", name, err)
+			}
+			if !e.Modified.IsZero() {
+				os.Chtimes(dest, e.Modified, e.Modified)
+			}
+			if e.Size > 0 {
+				n = e.Size // what the archive says, checked by VerifyConverted
+			}
+			out.Videos = append(out.Videos, dest)
+			out.videoSizes = append(out.videoSizes, n)
+			rep.Logf("info", "视频已移动到 %s", dest)
+			continue
+		}
+
+		if zw == nil {
+			if zf, err = os.Create(partPath); err != nil {
+				return out, fmt.Errorf("Zip failed: %w", err)
+			}
+			zw = newZipWriter(zf, level)
+		}
+		header := &zip.FileHeader{Name: name, Method: zipMethod(name, level), Modified: e.Modified}
+		_, err = streamEntry(ctx, r, buf, count, func() (io.WriteCloser, error) {
+			w, err := zw.CreateHeader(header)
+			return nopWriteCloser{w}, err
+		})
+		if err != nil {
+			return out, fmt.Errorf("%s: %w", name, err)
+		}
+		out.Files++
+	}
+	return out, nil
+}
+
+// Bytes copied at a time; the only per-entry memory besides the decoders
+const copyBufferSize = 1 << 20
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// streamEntry copies the current entry of r into the writer create returns
+// and says how many bytes that was. Read failures come back as sourceError.
+func streamEntry(ctx context.Context, r Reader, buf []byte, count io.Writer, create func() (io.WriteCloser, error)) (int64, error) {
+	w, err := create()
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.CopyBuffer(io.MultiWriter(w, count), &ctxReader{ctx: ctx, r: sourceReader{r}}, buf)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// sourceReader tags read errors of the archive as sourceError
+type sourceReader struct{ r io.Reader }
+
+func (s sourceReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = sourceError{err}
+	}
+	return n, err
+}
+
+// convertWithTempDir is the old way, used only with the external binary:
+// extract everything, then move the videos and zip the rest
+func convertWithTempDir(ctx context.Context, bin, src, videoOut string, level int, size int64, reported *int64, rep progress.Reporter) (Converted, error) {
+	var out Converted
+
+	tempDir, err := os.MkdirTemp("", "wcs_extract_")
+	if err != nil {
+		return out, fmt.Errorf("Temp dir error: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	n, err := extract7zExternal(ctx, bin, src, tempDir, size, *reported, rep)
+	*reported += n
+	if err != nil {
+		return out, err
+	}
+
+	// Scan and Move/Zip
+	var filesToZip []string
+	filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if !scanner.IsVideo(path) {
+			filesToZip = append(filesToZip, path)
+			return nil
+		}
+
+		out.videoCount++
+		destPath := uniquePath(filepath.Join(videoOut, info.Name()))
+		if err := moveFile(path, destPath); err != nil {
+			rep.Logf("error", "移动视频失败 %s: %v", info.Name(), err)
+		} else {
+			out.Videos = append(out.Videos, destPath)
+			out.videoSizes = append(out.videoSizes, info.Size())
+			rep.Logf("info", "视频已移动到 %s", destPath)
+		}
+		return nil
+	})
+
+	if len(filesToZip) == 0 {
+		// Only videos
+		return out, nil
+	}
+	zipPath := zipPathFor(src)
+	if err := ZipFiles(zipPath, filesToZip, tempDir, level, progress.Nop); err != nil {
+		return out, fmt.Errorf("Zip failed: %w", err)
+	}
+	out.Zip = zipPath
+	out.Files = len(filesToZip)
+	return out, nil
+}
+
+// VerifyConverted checks that out holds everything the archive did before the
+// original is removed: the zip reads back intact (VerifyZip) and every video of
+// the archive is in videoOut at its full size.
+func VerifyConverted(ctx context.Context, out Converted) error {
+	if out.Zip != "" {
+		if err := VerifyZip(ctx, out.Zip, out.Files); err != nil {
+			return err
+		}
+	}
+	if len(out.Videos) != out.videoCount {
+		return fmt.Errorf("%d of %d videos written", len(out.Videos), out.videoCount)
+	}
+	for i, v := range out.Videos {
+		info, err := os.Stat(v)
+		if err != nil {
+			return err
+		}
+		if info.Size() != out.videoSizes[i] {
+			return fmt.Errorf("%s: %d bytes, expected %d", filepath.Base(v), info.Size(), out.videoSizes[i])
+		}
+	}
+	return nil
+}
+
+// moveFile renames, or copies and deletes when src and dst are on different drives
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
+
+// checkFreeSpace fails when dir's drive has less than need bytes available.
+// Unknown free space (unsupported file system) is not an error.
+func checkFreeSpace(dir string, need int64) error {
+	if need <= 0 {
+		return nil
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		return nil
+	}
+	if free < uint64(need) {
+		return fmt.Errorf("not enough free space in %s: need %s, %s available", dir, formatSize(uint64(need)), formatSize(free))
+	}
+	return nil
+}
+
+func formatSize(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%d KB", n>>10)
+}
+
+// uniquePath appends _1, _2, ... to the name until it doesn't exist yet
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for counter := 1; ; counter++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s_%d%s", base, counter, ext)
+	}
+}
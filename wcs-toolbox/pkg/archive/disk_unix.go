@@ -0,0 +1,14 @@
+//go:build unix
+
+package archive
+
+import "syscall"
+
+// freeSpace returns the bytes available to the user on the drive holding dir
+func freeSpace(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package archive
+
+import "golang.org/x/sys/windows"
+
+// freeSpace returns the bytes available to the user on the drive holding dir
+func freeSpace(dir string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
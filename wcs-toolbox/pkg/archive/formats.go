@@ -0,0 +1,224 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"wcs-toolbox/pkg/scanner"
+)
+
+// ============ Formats ============
+
+// Entry is one file or folder inside an archive
+type Entry struct {
+	Name     string // as stored, '/' or '\' separated
+	Size     int64  // uncompressed
+	Modified time.Time
+	IsDir    bool
+}
+
+// Reader walks the entries of an archive in order, like archive/tar: after
+// Next, Read returns the contents of that entry.
+type Reader interface {
+	Next() (Entry, error) // io.EOF after the last entry
+	Read(p []byte) (int, error)
+	// Progress is how much of the archive has been read so far, out of total
+	Progress() (done, total int64)
+	Close() error
+}
+
+// Format is a reader for one kind of archive
+type Format struct {
+	Name string
+	// match sniffs the first bytes of the file; f is there for formats that
+	// need to look further, e.g. into a compressed stream
+	match func(f io.ReadSeeker, head []byte) bool
+	// list returns the entries without decoding any contents. nil when that
+	// needs a full pass, as with compressed tar streams.
+	list func(path, password string) ([]Entry, error)
+	open func(path, password string) (Reader, error)
+	// encrypted tells whether a read error came from missing the password;
+	// nil for formats without encryption
+	encrypted func(err error) bool
+	// external means the 7z binary can read the format too, as a fallback
+	external bool
+}
+
+// The order matters only in that the plain tar check (which looks at offset
+// 257) comes after the formats with a magic number at the start
+var formats = []*Format{
+	sevenZipFormat,
+	rarFormat,
+	tarFormat("tar.gz", gzipMagic, newGzipReader),
+	tarFormat("tar.xz", xzMagic, newXzReader),
+	tarFormat("tar.zst", zstdMagic, newZstdReader),
+	tarFormat("tar", "", nil),
+}
+
+func hasMagic(magic string) func(io.ReadSeeker, []byte) bool {
+	return func(_ io.ReadSeeker, head []byte) bool {
+		return strings.HasPrefix(string(head), magic)
+	}
+}
+
+// Detect identifies the archive at path by its content, not its name
+func Detect(path string) (*Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	for _, format := range formats {
+		if format.match(f, head) {
+			return format, nil
+		}
+	}
+	return nil, fmt.Errorf("not a supported archive: %s", filepath.Base(path))
+}
+
+// ============ Volumes ============
+
+var (
+	numberedVolume = regexp.MustCompile(`^(.+\.)(\d{3})$`)              // x.7z.001
+	rarPartVolume  = regexp.MustCompile(`(?i)^(.+\.part)(\d+)(\.rar)$`) // x.part1.rar
+	oldRarVolume   = regexp.MustCompile(`(?i)\.r\d{2}$`)                // x.rar, x.r00, x.r01...
+)
+
+// isLaterVolume reports whether name is the second or a later part of a
+// multi-volume archive; those are read through the first part
+func isLaterVolume(name string) bool {
+	if m := numberedVolume.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return n != 1
+	}
+	if m := rarPartVolume.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return n != 1
+	}
+	return oldRarVolume.MatchString(name)
+}
+
+// Volumes lists the files of the archive that starts at path: path itself,
+// then any further volumes next to it
+func Volumes(path string) []string {
+	vols := []string{path}
+	dir, name := filepath.Split(path)
+	following := func(prefix, number, suffix string) {
+		n, _ := strconv.Atoi(number)
+		for {
+			n++
+			next := filepath.Join(dir, fmt.Sprintf("%s%0*d%s", prefix, len(number), n, suffix))
+			if _, err := os.Stat(next); err != nil {
+				return
+			}
+			vols = append(vols, next)
+		}
+	}
+
+	if m := numberedVolume.FindStringSubmatch(name); m != nil {
+		following(m[1], m[2], "")
+	} else if m := rarPartVolume.FindStringSubmatch(name); m != nil {
+		following(m[1], m[2], m[3])
+	} else if strings.EqualFold(filepath.Ext(name), ".rar") {
+		base := strings.TrimSuffix(path, filepath.Ext(path))
+		for i := 0; i < 100; i++ {
+			next := fmt.Sprintf("%s.r%02d", base, i)
+			if _, err := os.Stat(next); err != nil {
+				break
+			}
+			vols = append(vols, next)
+		}
+	}
+	return vols
+}
+
+// VolumesSize is the combined size of the archive's volumes
+func VolumesSize(path string) int64 {
+	var size int64
+	for _, v := range Volumes(path) {
+		if info, err := os.Stat(v); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// Longest first so "x.tar.gz" loses ".tar.gz" rather than just ".gz"
+var archiveExts = []string{".tar.gz", ".tar.xz", ".tar.zst", ".tgz", ".txz", ".tzst", ".tar", ".7z", ".rar"}
+
+// zipPathFor names the zip after the archive, minus the volume number and
+// archive extensions: x.7z.001, x.part1.rar and x.tar.gz all become x.zip
+func zipPathFor(src string) string {
+	dir, name := filepath.Split(src)
+	if m := numberedVolume.FindStringSubmatch(name); m != nil {
+		name = strings.TrimSuffix(m[1], ".")
+	} else if m := rarPartVolume.FindStringSubmatch(name); m != nil {
+		name = m[1][:len(m[1])-len(".part")]
+	}
+
+	lower := strings.ToLower(name)
+	trimmed := strings.TrimSuffix(name, filepath.Ext(name))
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lower, ext) && len(name) > len(ext) {
+			trimmed = name[:len(name)-len(ext)]
+			break
+		}
+	}
+	return filepath.Join(dir, trimmed+".zip")
+}
+
+// Extensions Find doesn't bother opening. Scans run over whole media
+// libraries, and sniffing would read the header of every video and picture.
+var notArchiveExts = map[string]bool{
+	".zip": true, ".epub": true, ".cbz": true, ".pdf": true, ".txt": true, ".nfo": true,
+	".srt": true, ".ass": true, ".ssa": true, ".vtt": true, ".json": true, ".xml": true, ".html": true,
+	".mp3": true, ".flac": true, ".m4a": true, ".aac": true, ".wav": true, ".ogg": true,
+	".lnk": true, ".url": true, ".part": true,
+}
+
+// Smaller than the 7z signature header, let alone a tar block
+const minArchiveSize = 32
+
+// maybeArchive is Find's cheap check by name and size before Detect
+func maybeArchive(d fs.DirEntry) bool {
+	name := d.Name()
+	if isLaterVolume(name) || scanner.IsVideo(name) || scanner.IsImage(name) ||
+		notArchiveExts[strings.ToLower(filepath.Ext(name))] {
+		return false
+	}
+	info, err := d.Info()
+	return err == nil && info.Size() >= minArchiveSize
+}
+
+// Find lists the archives below rootPath, recognised by content (files that
+// are plainly something else by extension aren't opened). A multi-volume
+// archive is listed once, under its first volume, with the size of all its volumes.
+func Find(rootPath string) []scanner.FileInfo {
+	var files []scanner.FileInfo
+	filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !maybeArchive(d) {
+			return nil
+		}
+		if _, err := Detect(path); err != nil {
+			return nil
+		}
+		files = append(files, scanner.FileInfo{
+			Name: d.Name(),
+			Path: path,
+			Size: VolumesSize(path),
+		})
+		return nil
+	})
+	return files
+}
@@ -0,0 +1,18 @@
+package archive
+
+import (
+	"fmt"
+
+	"wcs-toolbox/pkg/progress"
+	"wcs-toolbox/pkg/scanner"
+)
+
+// PackImages zips the images of folderPath into dest at the given compression
+// level and returns how many it packed
+func PackImages(folderPath, dest string, level int, rep progress.Reporter) (int, error) {
+	images := scanner.Images(folderPath)
+	if len(images) == 0 {
+		return 0, fmt.Errorf("No images found")
+	}
+	return len(images), ZipFiles(dest, images, folderPath, level, rep)
+}
@@ -0,0 +1,80 @@
+package archive
+
+import (
+	"errors"
+
+	"github.com/nwaples/rardecode/v2"
+)
+
+// ============ rar ============
+
+// rardecode finds the further volumes (x.part2.rar, x.r00...) on its own
+var rarFormat = &Format{
+	Name:     "rar",
+	match:    hasMagic("Rar!\x1a\x07"), // followed by \x00 (RAR 4) or \x01\x00 (RAR 5)
+	list:     listRar,
+	open:     openRar,
+	external: true,
+	encrypted: func(err error) bool {
+		return errors.Is(err, rardecode.ErrArchiveEncrypted) ||
+			errors.Is(err, rardecode.ErrArchivedFileEncrypted) ||
+			errors.Is(err, rardecode.ErrBadPassword)
+	},
+}
+
+func listRar(path, password string) ([]Entry, error) {
+	files, err := rardecode.List(path, rardecode.Password(password))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(files))
+	for _, f := range files {
+		entries = append(entries, rarEntry(&f.FileHeader))
+	}
+	return entries, nil
+}
+
+func rarEntry(h *rardecode.FileHeader) Entry {
+	return Entry{Name: h.Name, Size: h.UnPackedSize, Modified: h.ModificationTime, IsDir: h.IsDir}
+}
+
+type rarReader struct {
+	rc          *rardecode.ReadCloser
+	done, total int64
+}
+
+func openRar(path, password string) (Reader, error) {
+	// The headers give the total for progress; solid archives can't skip ahead
+	// so everything is decoded in order anyway
+	entries, err := listRar(path, password)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := rardecode.OpenReader(path, rardecode.Password(password))
+	if err != nil {
+		return nil, err
+	}
+	r := &rarReader{rc: rc}
+	for _, e := range entries {
+		r.total += e.Size
+	}
+	return r, nil
+}
+
+func (r *rarReader) Next() (Entry, error) {
+	h, err := r.rc.Next()
+	if err != nil {
+		return Entry{}, err
+	}
+	return rarEntry(h), nil
+}
+
+func (r *rarReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.done += int64(n)
+	return n, err
+}
+
+func (r *rarReader) Progress() (int64, int64) { return r.done, r.total }
+
+func (r *rarReader) Close() error { return r.rc.Close() }
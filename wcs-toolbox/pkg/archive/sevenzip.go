@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/bodgit/sevenzip"
+
+	"wcs-toolbox/pkg/progress"
+)
+
+// ctxReader stops a copy once ctx is cancelled
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// external7z returns the 7z binary on PATH, if any
+func external7z() string {
+	for _, name := range []string{"7z", "7za"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// extract7zExternal runs bin on archive; already is the progress the failed
+// built-in attempt reported, so the bar continues from there
+func extract7zExternal(ctx context.Context, bin, archive, destDir string, size, already int64, rep progress.Reporter) (int64, error) {
+	var reported int64
+	remaining := size - already
+	cmd := exec.CommandContext(ctx, bin, "x", archive, "-o"+destDir, "-y", "-bsp1")
+	cmd.Stdout = &progress.PercentWriter{OnChange: func(delta int) {
+		n := remaining * int64(delta) / 100
+		reported += n
+		rep.Add(n)
+	}}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return reported, ctx.Err()
+		}
+		return reported, fmt.Errorf("7z extract failed: %w", err)
+	}
+	rep.Logf("info", "使用 %s 解压完成", filepath.Base(bin))
+	return reported, nil
+}
+
+// ============ 7z as a Format ============
+
+// sevenzip opens the further volumes of x.7z.001 on its own
+var sevenZipFormat = &Format{
+	Name:      "7z",
+	match:     hasMagic("7z\xbc\xaf\x27\x1c"),
+	list:      list7z,
+	open:      open7z,
+	encrypted: sevenZipEncrypted,
+	external:  true,
+}
+
+// sevenzip only hints at encryption; with a wrong password the hint may be
+// missing (a checksum error instead), which is why ConvertToZip keeps going
+// through the passwords on any read error once the archive is known to be encrypted
+func sevenZipEncrypted(err error) bool {
+	var re *sevenzip.ReadError
+	if errors.As(err, &re) {
+		return re.Encrypted
+	}
+	var rv sevenzip.ReadError
+	return errors.As(err, &rv) && rv.Encrypted
+}
+
+func list7z(path, password string) ([]Entry, error) {
+	r, err := sevenzip.OpenReaderWithPassword(path, password)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	entries := make([]Entry, 0, len(r.File))
+	for _, f := range r.File {
+		entries = append(entries, sevenZipEntry(f))
+	}
+	return entries, nil
+}
+
+func sevenZipEntry(f *sevenzip.File) Entry {
+	return Entry{Name: f.Name, Size: int64(f.UncompressedSize), Modified: f.Modified, IsDir: f.FileInfo().IsDir()}
+}
+
+type sevenZipReader struct {
+	r           *sevenzip.ReadCloser
+	next        int
+	rc          io.ReadCloser // the current entry
+	done, total int64
+}
+
+func open7z(path, password string) (Reader, error) {
+	r, err := sevenzip.OpenReaderWithPassword(path, password)
+	if err != nil {
+		return nil, err
+	}
+	z := &sevenZipReader{r: r}
+	for _, f := range r.File {
+		z.total += int64(f.UncompressedSize)
+	}
+	return z, nil
+}
+
+func (z *sevenZipReader) Next() (Entry, error) {
+	if z.rc != nil {
+		z.rc.Close()
+		z.rc = nil
+	}
+	if z.next >= len(z.r.File) {
+		return Entry{}, io.EOF
+	}
+	f := z.r.File[z.next]
+	z.next++
+	e := sevenZipEntry(f)
+	if !e.IsDir {
+		rc, err := f.Open()
+		if err != nil {
+			return e, err
+		}
+		z.rc = rc
+	}
+	return e, nil
+}
+
+func (z *sevenZipReader) Read(p []byte) (int, error) {
+	if z.rc == nil {
+		return 0, io.EOF
+	}
+	n, err := z.rc.Read(p)
+	z.done += int64(n)
+	return n, err
+}
+
+func (z *sevenZipReader) Progress() (int64, int64) { return z.done, z.total }
+
+func (z *sevenZipReader) Close() error {
+	if z.rc != nil {
+		z.rc.Close()
+	}
+	return z.r.Close()
+}
@@ -0,0 +1,181 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// ============ tar, tar.gz, tar.xz, tar.zst ============
+
+const (
+	gzipMagic = "\x1f\x8b"
+	xzMagic   = "\xfd7zXZ\x00"
+	zstdMagic = "\x28\xb5\x2f\xfd"
+)
+
+// decompressor wraps the raw stream of a compressed tar
+type decompressor func(r io.Reader) (io.ReadCloser, error)
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func newXzReader(r io.Reader) (io.ReadCloser, error) {
+	xr, err := xz.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(xr), nil
+}
+
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zr.IOReadCloser(), nil
+}
+
+func isTarHeader(head []byte) bool {
+	// "ustar\x00" (POSIX) or "ustar " (GNU)
+	return len(head) >= 262 && string(head[257:262]) == "ustar"
+}
+
+// tarFormat is a tar stream, compressed with decompress when magic is set
+func tarFormat(name, magic string, decompress decompressor) *Format {
+	format := &Format{
+		Name: name,
+		open: func(path, _ string) (Reader, error) { return openTar(path, decompress) },
+	}
+	if decompress == nil {
+		format.match = func(_ io.ReadSeeker, head []byte) bool { return isTarHeader(head) }
+		format.list = listTar
+		return format
+	}
+	// A .gz that isn't a tar is not ours: look at the start of the decompressed stream
+	format.match = func(f io.ReadSeeker, head []byte) bool {
+		if !hasMagic(magic)(f, head) {
+			return false
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return false
+		}
+		r, err := decompress(bufio.NewReader(f))
+		if err != nil {
+			return false
+		}
+		defer r.Close()
+		inner := make([]byte, 512)
+		n, _ := io.ReadFull(r, inner)
+		return isTarHeader(inner[:n])
+	}
+	return format
+}
+
+// listTar reads the headers of an uncompressed tar; archive/tar seeks past
+// the contents
+func listTar(path, _ string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	tr := tar.NewReader(f)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := tarEntry(h); ok {
+			entries = append(entries, e)
+		}
+	}
+}
+
+// tarEntry converts h; links, devices and the like are skipped
+func tarEntry(h *tar.Header) (Entry, bool) {
+	mode := h.FileInfo().Mode()
+	if !mode.IsRegular() && !mode.IsDir() {
+		return Entry{}, false
+	}
+	return Entry{Name: h.Name, Size: h.Size, Modified: h.ModTime, IsDir: mode.IsDir()}, true
+}
+
+type tarReader struct {
+	f    *os.File
+	raw  *countingReader
+	dec  io.Closer
+	tr   *tar.Reader
+	size int64
+}
+
+func openTar(path string, decompress decompressor) (Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Progress is measured on the compressed side, the only size known up front
+	t := &tarReader{f: f, raw: &countingReader{r: bufio.NewReaderSize(f, copyBufferSize)}, size: info.Size()}
+	var r io.Reader = t.raw
+	if decompress != nil {
+		dr, err := decompress(r)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		t.dec, r = dr, dr
+	}
+	t.tr = tar.NewReader(r)
+	return t, nil
+}
+
+func (t *tarReader) Next() (Entry, error) {
+	for {
+		h, err := t.tr.Next()
+		if err != nil {
+			return Entry{}, err
+		}
+		if e, ok := tarEntry(h); ok {
+			return e, nil
+		}
+	}
+}
+
+func (t *tarReader) Read(p []byte) (int, error) { return t.tr.Read(p) }
+
+func (t *tarReader) Progress() (int64, int64) { return t.raw.n, t.size }
+
+func (t *tarReader) Close() error {
+	if t.dec != nil {
+		t.dec.Close()
+	}
+	return t.f.Close()
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,139 @@
+// Package archive converts between archive formats and packs folders into zips.
+package archive
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wcs-toolbox/pkg/progress"
+	"wcs-toolbox/pkg/scanner"
+)
+
+// Compressed already: deflating them again costs time for next to nothing
+var storedExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".avif": true, ".heic": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".flac": true,
+	".zip": true, ".7z": true, ".rar": true, ".gz": true, ".tgz": true, ".xz": true, ".zst": true, ".bz2": true,
+	".epub": true, ".cbz": true, ".docx": true, ".xlsx": true, ".pptx": true,
+}
+
+// zipMethod is Store at level 0 and for media that is compressed already,
+// Deflate otherwise
+func zipMethod(name string, level int) uint16 {
+	if level <= 0 || scanner.IsVideo(name) || storedExts[strings.ToLower(filepath.Ext(name))] {
+		return zip.Store
+	}
+	return zip.Deflate
+}
+
+// newZipWriter deflates at level, 1 (fastest) to 9 (smallest)
+func newZipWriter(w io.Writer, level int) *zip.Writer {
+	zw := zip.NewWriter(w)
+	if level > 0 {
+		level = min(level, flate.BestCompression)
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+	return zw
+}
+
+// ZipFiles packs files under their path relative to baseDir at the given
+// compression level (0-9, see zipMethod), reporting each file's size to rep
+// once written
+func ZipFiles(dest string, files []string, baseDir string, level int, rep progress.Reporter) (err error) {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	w := newZipWriter(f, level)
+	// The central directory is only written on Close, so its error counts too
+	defer func() {
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dest) // no half-written zip left behind
+		}
+	}()
+
+	for _, file := range files {
+		rel, err := filepath.Rel(baseDir, file)
+		if err != nil {
+			rel = filepath.Base(file)
+		} // Fallback
+
+		if err := zipFile(w, file, filepath.ToSlash(rel), level, rep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipFile streams one file into w, so memory use doesn't grow with file size
+func zipFile(w *zip.Writer, file, name string, level int, rep progress.Reporter) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zipMethod(name, level)
+	zf, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(zf, in)
+	rep.Add(n)
+	return err
+}
+
+// VerifyZip reads every entry of the zip back, which checks its CRC, and
+// makes sure the zip holds files entries. Used before an original is removed.
+func VerifyZip(ctx context.Context, path string, files int) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	buf := make([]byte, copyBufferSize)
+	count := 0
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		_, err = io.CopyBuffer(io.Discard, &ctxReader{ctx: ctx, r: rc}, buf)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		count++
+	}
+	if count != files {
+		return fmt.Errorf("zip holds %d files, expected %d", count, files)
+	}
+	return nil
+}
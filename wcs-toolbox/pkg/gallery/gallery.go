@@ -0,0 +1,349 @@
+// Package gallery searches the gallery site and downloads galleries as zips.
+package gallery
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"wcs-toolbox/pkg/progress"
+)
+
+type Gallery struct {
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	ImageCount int    `json:"imageCount"`
+	Thumbnail  string `json:"thumbnail"`
+}
+
+type SearchResult struct {
+	Galleries   []Gallery
+	CurrentPage int
+	HasNextPage bool
+}
+
+type Client struct {
+	HTTP *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		HTTP: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+}
+
+// Search fetches one page of search results (pages start at 1)
+func (c *Client) Search(keyword string, page int) (SearchResult, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	searchUrl := fmt.Sprintf("https://www.hentaiclub.net/search/%s/", url.QueryEscape(keyword))
+	if page > 1 {
+		searchUrl = fmt.Sprintf("https://www.hentaiclub.net/search/%s/%d/", url.QueryEscape(keyword), page)
+	}
+
+	req, _ := http.NewRequest("GET", searchUrl, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return SearchResult{CurrentPage: page}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return SearchResult{CurrentPage: page}, fmt.Errorf("Status code: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return SearchResult{CurrentPage: page}, err
+	}
+
+	var galleries []Gallery
+	doc.Find(".item-link, a.item-link, .post-item a").Each(func(i int, s *goquery.Selection) {
+		href, exists := s.Attr("href")
+		if !exists {
+			return
+		}
+
+		if (strings.Contains(href, "/r15/") || strings.Contains(href, "/r18/")) && strings.HasSuffix(href, ".html") {
+			title := strings.TrimSpace(s.Find(".item-title, .post-title, h2, h3").Text())
+			if title == "" {
+				title, _ = s.Attr("title")
+			}
+			if title == "" {
+				title = strings.TrimSpace(s.Text())
+			}
+
+			// Extract image count
+			count := 0
+			// Regex \[(\d+)P\]
+			// Simplified extraction
+			if idx := strings.LastIndex(title, "["); idx != -1 {
+				part := title[idx:] // [123P]
+				if idx2 := strings.Index(part, "P]"); idx2 != -1 {
+					cStr := part[1:idx2]
+					count, _ = strconv.Atoi(cStr)
+				}
+			}
+
+			thumb, _ := s.Find("img").Attr("src")
+			if thumb == "" {
+				thumb, _ = s.Find("img").Attr("data-src")
+			}
+
+			if !strings.HasPrefix(href, "http") {
+				href = "https://www.hentaiclub.net" + href
+			}
+
+			galleries = append(galleries, Gallery{
+				URL:        href,
+				Title:      title,
+				ImageCount: count,
+				Thumbnail:  thumb,
+			})
+		}
+	})
+
+	// Deduplicate
+	unique := make([]Gallery, 0, len(galleries))
+	seen := make(map[string]bool)
+	for _, g := range galleries {
+		if !seen[g.URL] {
+			seen[g.URL] = true
+			unique = append(unique, g)
+		}
+	}
+
+	// Check HasNextPage
+	hasNext := false
+	doc.Find("a[href*='/search/']").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if strings.Contains(href, fmt.Sprintf("/%d/", page+1)) {
+			hasNext = true
+		}
+	})
+
+	return SearchResult{
+		Galleries:   unique,
+		CurrentPage: page,
+		HasNextPage: hasNext,
+	}, nil
+}
+
+// SearchAll loads up to maxPages pages of results, de-duplicated, stopping at the
+// last page, on the first error or when ctx is cancelled. onPage, if set, is
+// called after each page. It returns the galleries, pages loaded and whether
+// more pages exist.
+func (c *Client) SearchAll(ctx context.Context, keyword string, maxPages int, onPage func(page, found int)) ([]Gallery, int, bool) {
+	var allGalleries []Gallery
+	seen := make(map[string]bool)
+
+	currentPage := 1
+	hasMore := true
+
+	for currentPage <= maxPages && hasMore {
+		if ctx.Err() != nil {
+			break
+		}
+
+		res, err := c.Search(keyword, currentPage)
+		if err != nil {
+			break
+		}
+
+		for _, g := range res.Galleries {
+			if !seen[g.URL] {
+				seen[g.URL] = true
+				allGalleries = append(allGalleries, g)
+			}
+		}
+		if onPage != nil {
+			onPage(currentPage, len(allGalleries))
+		}
+
+		hasMore = res.HasNextPage
+		currentPage++
+		time.Sleep(500 * time.Millisecond) // Delay
+	}
+
+	return allGalleries, currentPage - 1, hasMore && currentPage <= maxPages
+}
+
+// Download fetches every image of g into "<title>.zip" in outputPath and returns
+// the zip's path. Downloaded bytes go to rep; onImage, if set, is called after each
+// image with the number downloaded so far.
+func (c *Client) Download(ctx context.Context, g Gallery, outputPath string, rep progress.Reporter, onImage func(downloaded, total int)) (string, error) {
+	// Fetch images
+	req, _ := http.NewRequest("GET", g.URL, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var images []string
+
+	// Selectors
+	doc.Find("#masonry .post-item img, .post-item-img, .post-content img, article img").Each(func(i int, s *goquery.Selection) {
+		src, _ := s.Attr("data-src")
+		if src == "" {
+			src, _ = s.Attr("src")
+		}
+		if src != "" && !strings.Contains(src, "logo") && !strings.Contains(src, "ads") {
+			if !strings.HasPrefix(src, "http") {
+				src = "https:" + src
+			}
+			images = append(images, src)
+		}
+	})
+
+	if len(images) == 0 {
+		return "", fmt.Errorf("no images found")
+	}
+
+	// Download
+	// Create Zip
+	safeTitle := SanitizeFilename(g.Title)
+	if len(safeTitle) > 100 {
+		safeTitle = safeTitle[:100]
+	}
+	zipPath := filepath.Join(outputPath, safeTitle+".zip")
+
+	// Prepare for zip
+	f, err := os.Create(zipPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	defer zw.Close()
+
+	// Concurrent config
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 5) // max concurrent
+	var downloaded int
+	var writeErr error
+	var mu sync.Mutex
+
+	for j, imgUrl := range images {
+		if ctx.Err() != nil {
+			break // workers still write to zw, wait for them below
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(idx int, u string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer func() {
+				if r := recover(); r != nil {
+					rep.Logf("error", "下载 %s 时出错: %v", u, r)
+				}
+			}()
+
+			// Download
+			data, err := c.get(u, rep)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if writeErr != nil {
+				return
+			}
+			// Write to zip
+			ext := filepath.Ext(parseUrlPath(u))
+			if ext == "" {
+				ext = ".jpg"
+			} // Default
+			fname := fmt.Sprintf("%04d%s", idx+1, ext)
+
+			zipFile, err := zw.Create(fname)
+			if err == nil {
+				_, err = zipFile.Write(data)
+			}
+			if err != nil {
+				writeErr = err
+				return
+			}
+
+			downloaded++
+			if onImage != nil {
+				onImage(downloaded, len(images))
+			}
+		}(j, imgUrl)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if writeErr != nil {
+		return "", writeErr
+	}
+	if downloaded == 0 {
+		return "", fmt.Errorf("all downloads failed")
+	}
+	return zipPath, nil
+}
+
+// SanitizeFilename replaces characters that are invalid in file names
+func SanitizeFilename(name string) string {
+	reg := regexp.MustCompile(`[<>:"/\\|?*]`)
+	return reg.ReplaceAllString(name, "_")
+}
+
+func parseUrlPath(u string) string {
+	parsed, _ := url.Parse(u)
+	return parsed.Path
+}
+
+// get fetches u, reporting its Content-Length (when known) as more work and
+// each chunk read as done
+func (c *Client) get(u string, rep progress.Reporter) ([]byte, error) {
+	req, _ := http.NewRequest("GET", u, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	req.Header.Set("Referer", "https://www.hentaiclub.net/")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength > 0 {
+		rep.AddTotal(resp.ContentLength)
+	}
+	return io.ReadAll(io.TeeReader(resp.Body, progress.Counter{Add: rep.Add}))
+}
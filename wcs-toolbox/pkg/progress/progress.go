@@ -0,0 +1,60 @@
+// Package progress defines how the tools report what they are doing, so they
+// can run under the GUI task queue, the CLI or any other program.
+package progress
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// Reporter receives progress from a running operation. Implementations must be
+// safe for concurrent use; downloads report from several goroutines.
+type Reporter interface {
+	// AddTotal announces n more bytes of work, for sizes learned on the way
+	AddTotal(n int64)
+	// Add reports n more bytes processed
+	Add(n int64)
+	// Logf records a message; level is info, warn or error
+	Logf(level string, format string, args ...interface{})
+}
+
+// Nop discards everything
+var Nop Reporter = nop{}
+
+type nop struct{}
+
+func (nop) AddTotal(int64)                      {}
+func (nop) Add(int64)                           {}
+func (nop) Logf(string, string, ...interface{}) {}
+
+// Counter is an io.Writer that reports the length of every write to Add.
+// Useful with io.TeeReader / io.MultiWriter.
+type Counter struct {
+	Add func(n int64)
+}
+
+func (c Counter) Write(p []byte) (int, error) {
+	c.Add(int64(len(p)))
+	return len(p), nil
+}
+
+var percentPattern = regexp.MustCompile(`(\d{1,3})%`)
+
+// PercentWriter parses percentage output such as the "-bsp1" progress of 7z
+// ("  42% 12 - file") and reports each increase
+type PercentWriter struct {
+	Last     int
+	OnChange func(delta int)
+}
+
+func (w *PercentWriter) Write(p []byte) (int, error) {
+	matches := percentPattern.FindAllSubmatch(p, -1)
+	if len(matches) > 0 {
+		pct, _ := strconv.Atoi(string(matches[len(matches)-1][1]))
+		if pct > w.Last && pct <= 100 {
+			w.OnChange(pct - w.Last)
+			w.Last = pct
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,155 @@
+// Package scanner finds the inputs of the tools: videos, archives, text files
+// and folders of images.
+package scanner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type VideoFile struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	Size         int64  `json:"size"`
+	ParentFolder string `json:"parentFolder"`
+}
+
+type FileInfo struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+type FolderInfo struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	ImageCount int    `json:"imageCount"`
+	TotalSize  int64  `json:"totalSize"`
+}
+
+var videoExts = map[string]bool{
+	".mp4": true, ".mkv": true, ".avi": true, ".mov": true, ".wmv": true, ".flv": true, ".webm": true, ".m4v": true, ".ts": true,
+}
+
+var imageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".webp": true,
+}
+
+func IsVideo(path string) bool {
+	return videoExts[strings.ToLower(filepath.Ext(path))]
+}
+
+func IsImage(path string) bool {
+	return imageExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// Videos lists every video below rootPath together with its parent folder name
+func Videos(rootPath string) []VideoFile {
+	var videos []VideoFile
+
+	filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() && IsVideo(path) {
+			parent := filepath.Base(filepath.Dir(path))
+			if filepath.Dir(path) == rootPath {
+				parent = filepath.Base(rootPath)
+			}
+
+			info, _ := d.Info()
+			videos = append(videos, VideoFile{
+				Name:         d.Name(),
+				Path:         path,
+				Size:         info.Size(),
+				ParentFolder: parent,
+			})
+		}
+		return nil
+	})
+	return videos
+}
+
+// Files lists every file below rootPath whose name ends in ext (case-insensitive)
+func Files(rootPath string, ext string) []FileInfo {
+	var files []FileInfo
+	ext = strings.ToLower(ext)
+	filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() && strings.HasSuffix(strings.ToLower(d.Name()), ext) {
+			info, _ := d.Info()
+			files = append(files, FileInfo{
+				Name: d.Name(),
+				Path: path,
+				Size: info.Size(),
+			})
+		}
+		return nil
+	})
+	return files
+}
+
+// ImageFolders lists the folders below rootPath that directly contain images
+func ImageFolders(rootPath string) []FolderInfo {
+	var folders []FolderInfo
+
+	filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path != rootPath {
+			if folder, ok := ImageFolder(path); ok {
+				folders = append(folders, folder)
+			}
+		}
+		return nil
+	})
+	return folders
+}
+
+// ImageFolder reports whether path directly contains images
+func ImageFolder(path string) (FolderInfo, bool) {
+	imgCount := 0
+	var totalSize int64 = 0
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return FolderInfo{}, false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && IsImage(entry.Name()) {
+			imgCount++
+			info, _ := entry.Info()
+			totalSize += info.Size()
+		}
+	}
+
+	if imgCount == 0 {
+		return FolderInfo{}, false
+	}
+	return FolderInfo{
+		Name:       filepath.Base(path),
+		Path:       path,
+		ImageCount: imgCount,
+		TotalSize:  totalSize,
+	}, true
+}
+
+// Images lists the images below folderPath
+func Images(folderPath string) []string {
+	var images []string
+	filepath.WalkDir(folderPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() && IsImage(path) {
+			images = append(images, path)
+		}
+		return nil
+	})
+	return images
+}
@@ -0,0 +1,54 @@
+// Package shortcut creates links to files: .lnk shortcuts on Windows, symlinks elsewhere.
+package shortcut
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// LinkName names the link for video name found in parentFolder:
+//
+//	folder      "<parent>_<name>"
+//	folderOnly  "<parent><ext>", numbered if it already exists in targetDir
+//	otherwise   the video's own name
+func LinkName(mode, targetDir, parentFolder, name string) string {
+	ext := filepath.Ext(name)
+
+	switch mode {
+	case "folder":
+		return fmt.Sprintf("%s_%s", parentFolder, name)
+	case "folderOnly":
+		linkName := fmt.Sprintf("%s%s", parentFolder, ext)
+		// Handle dupe
+		counter := 1
+		for {
+			if _, err := os.Stat(filepath.Join(targetDir, linkName)); os.IsNotExist(err) {
+				break
+			}
+			linkName = fmt.Sprintf("%s_%d%s", parentFolder, counter, ext)
+			counter++
+		}
+		return linkName
+	default:
+		return name
+	}
+}
+
+// Create links dst to src and returns the path actually created (".lnk" is appended on Windows)
+func Create(src, dst string) (string, error) {
+	os.MkdirAll(filepath.Dir(dst), 0755)
+	if runtime.GOOS == "windows" {
+		if !strings.HasSuffix(strings.ToLower(dst), ".lnk") {
+			dst += ".lnk"
+		}
+		// Powershell shortcut creation
+		psScript := fmt.Sprintf("$s=(New-Object -COM WScript.Shell).CreateShortcut('%s');$s.TargetPath='%s';$s.Save()", dst, src)
+		cmd := exec.Command("powershell", "-Command", psScript)
+		return dst, cmd.Run()
+	}
+	return dst, os.Symlink(src, dst)
+}
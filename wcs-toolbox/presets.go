@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"wcs-toolbox/pkg/archive"
+	"wcs-toolbox/pkg/scanner"
+)
+
+// ============ Presets ============
+
+// Preset is a named set of task options. Data holds the params of Type without
+// items; the items come from scanning a folder when the preset is enqueued.
+type Preset struct {
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	CreatedAt int64       `json:"createdAt"`
+	UpdatedAt int64       `json:"updatedAt"`
+}
+
+func (a *App) loadPresets() {
+	a.presetsMutex.Lock()
+	defer a.presetsMutex.Unlock()
+
+	a.presets = make(map[string]Preset)
+	dir, err := appDataDir()
+	if err != nil {
+		return
+	}
+	a.presetsPath = filepath.Join(dir, "presets.json")
+
+	raw, err := os.ReadFile(a.presetsPath)
+	if err != nil {
+		return
+	}
+	var list []Preset
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return
+	}
+	for _, p := range list {
+		a.presets[p.Name] = p
+	}
+}
+
+// savePresetsLocked writes the presets; caller must hold presetsMutex
+func (a *App) savePresetsLocked() error {
+	if a.presetsPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(a.presetListLocked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.presetsPath, data)
+}
+
+func (a *App) presetListLocked() []Preset {
+	list := make([]Preset, 0, len(a.presets))
+	for _, p := range a.presets {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// normalizePreset checks the options and drops any items from Data
+func normalizePreset(p Preset) (Preset, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return p, fmt.Errorf("preset name is required")
+	}
+	if p.Type == "crawl-gallery" {
+		return p, fmt.Errorf("crawl-gallery tasks cannot be saved as presets")
+	}
+	params, err := decodeTaskParams(p.Type, p.Data)
+	if err != nil {
+		return p, err
+	}
+	p.Data = params.withItems(func(string) bool { return false })
+	return p, nil
+}
+
+func (a *App) PresetList() []Preset {
+	a.presetsMutex.Lock()
+	defer a.presetsMutex.Unlock()
+	return a.presetListLocked()
+}
+
+func (a *App) PresetGet(name string) (Preset, error) {
+	a.presetsMutex.Lock()
+	defer a.presetsMutex.Unlock()
+	p, ok := a.presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("preset %q not found", name)
+	}
+	return p, nil
+}
+
+// PresetSave creates a preset or overwrites the one with the same name
+func (a *App) PresetSave(p Preset) (Preset, error) {
+	p, err := normalizePreset(p)
+	if err != nil {
+		return Preset{}, err
+	}
+
+	a.presetsMutex.Lock()
+	defer a.presetsMutex.Unlock()
+	now := time.Now().UnixMilli()
+	p.CreatedAt = now
+	if old, ok := a.presets[p.Name]; ok {
+		p.CreatedAt = old.CreatedAt
+	}
+	p.UpdatedAt = now
+	a.presets[p.Name] = p
+	return p, a.savePresetsLocked()
+}
+
+// PresetUpdate edits the preset called name; p.Name may differ to rename it
+func (a *App) PresetUpdate(name string, p Preset) (Preset, error) {
+	p, err := normalizePreset(p)
+	if err != nil {
+		return Preset{}, err
+	}
+
+	a.presetsMutex.Lock()
+	defer a.presetsMutex.Unlock()
+	old, ok := a.presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("preset %q not found", name)
+	}
+	if _, taken := a.presets[p.Name]; taken && p.Name != name {
+		return Preset{}, fmt.Errorf("preset %q already exists", p.Name)
+	}
+	p.CreatedAt = old.CreatedAt
+	p.UpdatedAt = time.Now().UnixMilli()
+	delete(a.presets, name)
+	a.presets[p.Name] = p
+	return p, a.savePresetsLocked()
+}
+
+func (a *App) PresetDelete(name string) error {
+	a.presetsMutex.Lock()
+	defer a.presetsMutex.Unlock()
+	if _, ok := a.presets[name]; !ok {
+		return fmt.Errorf("preset %q not found", name)
+	}
+	delete(a.presets, name)
+	return a.savePresetsLocked()
+}
+
+// PresetSaveFromTask saves the options of an existing task under name
+func (a *App) PresetSaveFromTask(id int, name string) (Preset, error) {
+	a.tasksMutex.Lock()
+	task, ok := a.tasks[id]
+	var taskType string
+	var data interface{}
+	if ok {
+		taskType, data = task.Type, task.Data
+	}
+	a.tasksMutex.Unlock()
+	if !ok {
+		return Preset{}, fmt.Errorf("task %d not found", id)
+	}
+	return a.PresetSave(Preset{Name: name, Type: taskType, Data: data})
+}
+
+// PresetEnqueue scans folder for the preset's kind of items and enqueues them
+// with the preset's options
+func (a *App) PresetEnqueue(name string, folder string) (int, error) {
+	p, err := a.PresetGet(name)
+	if err != nil {
+		return 0, err
+	}
+	params, err := decodeTaskParams(p.Type, p.Data)
+	if err != nil {
+		return 0, err
+	}
+	params, err = scanTaskItems(params, folder)
+	if err != nil {
+		return 0, err
+	}
+	if len(params.itemKeys()) == 0 {
+		return 0, fmt.Errorf("no items found in %s", folder)
+	}
+	return a.addTask(&Task{
+		Type: p.Type,
+		Name: fmt.Sprintf("%s: %s", p.Name, filepath.Base(folder)),
+		Data: params,
+	}), nil
+}
+
+// scanTaskItems returns a copy of params whose items are what folder holds
+// for that task type
+func scanTaskItems(params taskParams, folder string) (taskParams, error) {
+	if info, err := os.Stat(folder); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", folder)
+	}
+
+	switch p := params.withItems(func(string) bool { return false }).(type) {
+	case *CreateShortcutsParams:
+		p.Videos = scanner.Videos(folder)
+		return p, nil
+	case *Convert7zParams:
+		p.Files = archive.Find(folder)
+		return p, nil
+	case *PackImagesParams:
+		// The folder itself may hold the images
+		if f, ok := scanner.ImageFolder(folder); ok {
+			p.Folders = append(p.Folders, f)
+		}
+		p.Folders = append(p.Folders, scanner.ImageFolders(folder)...)
+		return p, nil
+	case *ConvertTxtParams:
+		p.Files = scanner.Files(folder, ".txt")
+		return p, nil
+	}
+	return nil, fmt.Errorf("this task type cannot scan a folder")
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============ Scheduled Tasks ============
+
+// Schedule re-scans Folder at set times and enqueues whatever it has not
+// enqueued before. The options come from Preset, or from Type/Data when no
+// preset is named (Data without items, as in a preset).
+type Schedule struct {
+	ID         int         `json:"id"`
+	Name       string      `json:"name"`
+	Preset     string      `json:"preset,omitempty"`
+	Type       string      `json:"type,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Folder     string      `json:"folder"`
+	Cron       string      `json:"cron,omitempty"`  // "min hour day month weekday" or @hourly, @daily, @weekly, @monthly
+	RunAt      int64       `json:"runAt,omitempty"` // one-off run (unix ms) when Cron is empty
+	Enabled    bool        `json:"enabled"`
+	NextRun    int64       `json:"nextRun,omitempty"`
+	LastRun    int64       `json:"lastRun,omitempty"`
+	LastTaskID int         `json:"lastTaskId,omitempty"`
+	LastError  string      `json:"lastError,omitempty"`
+	Seen       []string    `json:"seen,omitempty"` // items already enqueued, pruned to what the last scan found
+	firing     bool
+}
+
+// scheduleSnapshot is the on-disk layout of schedules.json, kept next to tasks.json
+type scheduleSnapshot struct {
+	Seq       int         `json:"seq"`
+	Schedules []*Schedule `json:"schedules"`
+}
+
+// Longest the scheduler sleeps, so clock changes and suspend are noticed
+const maxSchedulerSleep = time.Minute
+
+func (a *App) loadSchedules() {
+	a.schedulesMutex.Lock()
+	defer a.schedulesMutex.Unlock()
+
+	a.schedules = make(map[int]*Schedule)
+	dir, err := appDataDir()
+	if err != nil {
+		return
+	}
+	a.schedulesPath = filepath.Join(dir, "schedules.json")
+
+	raw, err := os.ReadFile(a.schedulesPath)
+	if err != nil {
+		return
+	}
+	var snap scheduleSnapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return
+	}
+	a.scheduleIdSeq = snap.Seq
+	for _, s := range snap.Schedules {
+		if s.ID > a.scheduleIdSeq {
+			a.scheduleIdSeq = s.ID
+		}
+		// A run missed while the app was closed fires once at startup
+		a.schedules[s.ID] = s
+	}
+}
+
+// saveSchedulesLocked writes the schedules; caller must hold schedulesMutex
+func (a *App) saveSchedulesLocked() error {
+	if a.schedulesPath == "" {
+		return nil
+	}
+	snap := scheduleSnapshot{Seq: a.scheduleIdSeq, Schedules: a.scheduleListLocked()}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.schedulesPath, data)
+}
+
+func (a *App) scheduleListLocked() []*Schedule {
+	list := make([]*Schedule, 0, len(a.schedules))
+	for _, s := range a.schedules {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// schedulesChangedLocked saves and tells the frontend; caller must hold schedulesMutex
+func (a *App) schedulesChangedLocked() error {
+	err := a.saveSchedulesLocked()
+	a.wakeScheduler()
+	a.emitEvent("schedule-list-update", a.scheduleCopiesLocked())
+	return err
+}
+
+func (a *App) scheduleCopiesLocked() []Schedule {
+	list := []Schedule{}
+	for _, s := range a.scheduleListLocked() {
+		c := *s
+		c.Seen = nil // can be long; only the backend needs it
+		list = append(list, c)
+	}
+	return list
+}
+
+func (a *App) wakeScheduler() {
+	select {
+	case a.scheduleWake <- struct{}{}:
+	default:
+	}
+}
+
+// normalizeSchedule checks s and works out its first run
+func (a *App) normalizeSchedule(s Schedule, now time.Time) (Schedule, error) {
+	s.Folder = strings.TrimSpace(s.Folder)
+	if s.Folder == "" {
+		return s, fmt.Errorf("folder is required")
+	}
+	if info, err := os.Stat(s.Folder); err != nil {
+		return s, err
+	} else if !info.IsDir() {
+		return s, fmt.Errorf("%s is not a folder", s.Folder)
+	}
+
+	if s.Preset != "" {
+		if _, err := a.PresetGet(s.Preset); err != nil {
+			return s, err
+		}
+		s.Type, s.Data = "", nil
+	} else {
+		p, err := normalizePreset(Preset{Name: "schedule", Type: s.Type, Data: s.Data})
+		if err != nil {
+			return s, err
+		}
+		s.Data = p.Data
+	}
+
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		s.Name = filepath.Base(s.Folder)
+	}
+
+	s.Cron = strings.TrimSpace(s.Cron)
+	switch {
+	case s.Cron != "":
+		spec, err := parseCron(s.Cron)
+		if err != nil {
+			return s, err
+		}
+		next := spec.next(now)
+		if next.IsZero() {
+			return s, fmt.Errorf("cron %q never matches", s.Cron)
+		}
+		s.RunAt = 0
+		s.NextRun = next.UnixMilli()
+	case s.RunAt > 0:
+		s.NextRun = s.RunAt
+	default:
+		return s, fmt.Errorf("cron or runAt is required")
+	}
+	if !s.Enabled {
+		s.NextRun = 0
+	}
+	return s, nil
+}
+
+func (a *App) ScheduleList() []Schedule {
+	a.schedulesMutex.Lock()
+	defer a.schedulesMutex.Unlock()
+	return a.scheduleCopiesLocked()
+}
+
+func (a *App) ScheduleAdd(s Schedule) (Schedule, error) {
+	s, err := a.normalizeSchedule(s, time.Now())
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	a.schedulesMutex.Lock()
+	defer a.schedulesMutex.Unlock()
+	a.scheduleIdSeq++
+	s.ID = a.scheduleIdSeq
+	s.LastRun, s.LastTaskID, s.LastError, s.Seen = 0, 0, "", nil
+	a.schedules[s.ID] = &s
+	return s, a.schedulesChangedLocked()
+}
+
+// ScheduleUpdate replaces the settings of a schedule. The record of enqueued
+// items is kept unless the folder changes.
+func (a *App) ScheduleUpdate(id int, s Schedule) (Schedule, error) {
+	s, err := a.normalizeSchedule(s, time.Now())
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	a.schedulesMutex.Lock()
+	defer a.schedulesMutex.Unlock()
+	old, ok := a.schedules[id]
+	if !ok {
+		return Schedule{}, fmt.Errorf("schedule %d not found", id)
+	}
+	s.ID = id
+	s.LastRun, s.LastTaskID, s.LastError = old.LastRun, old.LastTaskID, old.LastError
+	if s.Folder == old.Folder {
+		s.Seen = old.Seen
+	}
+	s.firing = old.firing
+	*old = s
+	return s, a.schedulesChangedLocked()
+}
+
+func (a *App) ScheduleDelete(id int) error {
+	a.schedulesMutex.Lock()
+	defer a.schedulesMutex.Unlock()
+	if _, ok := a.schedules[id]; !ok {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	delete(a.schedules, id)
+	return a.schedulesChangedLocked()
+}
+
+// ScheduleRunNow scans the folder right away without touching the next run.
+// Returns the id of the new task, or 0 if there was nothing new.
+func (a *App) ScheduleRunNow(id int) (int, error) {
+	return a.fireSchedule(id, false)
+}
+
+// ScheduleForget clears the record of enqueued items so the next run takes
+// everything in the folder again
+func (a *App) ScheduleForget(id int) error {
+	a.schedulesMutex.Lock()
+	defer a.schedulesMutex.Unlock()
+	s, ok := a.schedules[id]
+	if !ok {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	s.Seen = nil
+	return a.schedulesChangedLocked()
+}
+
+// runScheduler fires due schedules; started once next to processTasks
+func (a *App) runScheduler() {
+	for {
+		now := time.Now()
+		var due []int
+		wait := maxSchedulerSleep
+
+		a.schedulesMutex.Lock()
+		for _, s := range a.schedules {
+			if !s.Enabled || s.NextRun == 0 || s.firing {
+				continue
+			}
+			at := time.UnixMilli(s.NextRun)
+			if !at.After(now) {
+				due = append(due, s.ID)
+			} else if d := at.Sub(now); d < wait {
+				wait = d
+			}
+		}
+		a.schedulesMutex.Unlock()
+
+		sort.Ints(due)
+		for _, id := range due {
+			a.fireSchedule(id, true)
+		}
+		if len(due) > 0 {
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-a.scheduleWake:
+			timer.Stop()
+		}
+	}
+}
+
+// fireSchedule scans the schedule's folder and enqueues the items it has not
+// seen yet. advance moves NextRun on (scheduled runs) and disables one-off schedules.
+func (a *App) fireSchedule(id int, advance bool) (int, error) {
+	now := time.Now()
+
+	a.schedulesMutex.Lock()
+	s, ok := a.schedules[id]
+	if !ok {
+		a.schedulesMutex.Unlock()
+		return 0, fmt.Errorf("schedule %d not found", id)
+	}
+	if s.firing {
+		a.schedulesMutex.Unlock()
+		return 0, fmt.Errorf("schedule %d is already running", id)
+	}
+	s.firing = true
+	if advance {
+		if spec, err := parseCron(s.Cron); s.Cron != "" && err == nil && !spec.next(now).IsZero() {
+			s.NextRun = spec.next(now).UnixMilli()
+		} else {
+			s.Enabled = false
+			s.NextRun = 0
+		}
+	}
+	snap := *s
+	seen := make(map[string]bool, len(s.Seen))
+	for _, k := range s.Seen {
+		seen[k] = true
+	}
+	a.schedulesChangedLocked()
+	a.schedulesMutex.Unlock()
+
+	taskID, keys, err := a.enqueueNewItems(snap, seen)
+
+	a.schedulesMutex.Lock()
+	defer a.schedulesMutex.Unlock()
+	s, ok = a.schedules[id]
+	if !ok {
+		return taskID, err // deleted meanwhile
+	}
+	s.firing = false
+	s.LastRun = now.UnixMilli()
+	s.LastError = ""
+	if err != nil {
+		s.LastError = err.Error()
+	} else {
+		s.Seen = keys
+	}
+	if taskID != 0 {
+		s.LastTaskID = taskID
+	}
+	a.schedulesChangedLocked()
+	return taskID, err
+}
+
+// enqueueNewItems returns the new task (0 if nothing was new) and the keys of
+// everything the scan found, which become the schedule's seen list
+func (a *App) enqueueNewItems(s Schedule, seen map[string]bool) (int, []string, error) {
+	taskType, data := s.Type, s.Data
+	if s.Preset != "" {
+		p, err := a.PresetGet(s.Preset)
+		if err != nil {
+			return 0, nil, err
+		}
+		taskType, data = p.Type, p.Data
+	}
+	params, err := decodeTaskParams(taskType, data)
+	if err != nil {
+		return 0, nil, err
+	}
+	params, err = scanTaskItems(params, s.Folder)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	keys := params.itemKeys()
+	fresh := params.withItems(func(k string) bool { return !seen[k] })
+	if len(fresh.itemKeys()) == 0 {
+		return 0, keys, nil
+	}
+	id := a.addTask(&Task{
+		Type: taskType,
+		Name: fmt.Sprintf("%s: %s", s.Name, time.Now().Format("2006-01-02 15:04")),
+		Data: fresh,
+	})
+	return id, keys, nil
+}
+
+// ---- Cron spec ----
+
+// cronSpec holds the allowed values of each field as bit sets
+type cronSpec struct {
+	minute, hour, day, month, weekday uint64
+	anyDay, anyWeekday                bool
+}
+
+var cronAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// parseCron reads the usual five fields: minute, hour, day of month, month,
+// day of week (0 or 7 = Sunday). Each field takes *, n, a-b, lists and /step.
+func parseCron(expr string) (cronSpec, error) {
+	if alias, ok := cronAliases[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = alias
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSpec{}, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	var spec cronSpec
+	var err error
+	bounds := []struct {
+		dst      *uint64
+		min, max int
+	}{
+		{&spec.minute, 0, 59},
+		{&spec.hour, 0, 23},
+		{&spec.day, 1, 31},
+		{&spec.month, 1, 12},
+		{&spec.weekday, 0, 7},
+	}
+	for i, b := range bounds {
+		if *b.dst, err = parseCronField(fields[i], b.min, b.max); err != nil {
+			return cronSpec{}, fmt.Errorf("cron %q: %v", expr, err)
+		}
+	}
+	if spec.weekday&(1<<7) != 0 {
+		spec.weekday |= 1 // 7 is Sunday too
+	}
+	spec.anyDay = fields[2] == "*"
+	spec.anyWeekday = fields[4] == "*"
+	return spec, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var errA, errB error
+			lo, errA = strconv.Atoi(a)
+			hi, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rng)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max // "5/15" means from 5 on
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", rng, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches follows cron: when both day fields are restricted, either may match
+func (c cronSpec) dayMatches(t time.Time) bool {
+	dom := c.day&(1<<uint(t.Day())) != 0
+	dow := c.weekday&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return dow
+	case c.anyWeekday:
+		return dom
+	}
+	return dom || dow
+}
+
+// next returns the first matching minute after t, in t's location, or the
+// zero time if the spec can never match (e.g. "0 0 30 2 *", Feb 30)
+func (c cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Five years covers every valid spec, including Feb 29
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ============ Settings ============
+
+// settingsVersion is the current layout of settings.json. Bump it and add an
+// entry to settingsMigrations whenever a field is renamed or changes meaning.
+const settingsVersion = 1
+
+// settingsMigrations[v] upgrades the raw JSON of version v to v+1. Fields that
+// are merely added need no migration; they fall back to defaultSettings.
+var settingsMigrations = map[int]func(raw map[string]interface{}){}
+
+// How many folders each recent list keeps
+const maxRecentFolders = 10
+
+type Settings struct {
+	Version       int                `json:"version"`
+	Shortcuts     ShortcutsSettings  `json:"shortcuts"`
+	Convert7z     Convert7zSettings  `json:"convert7z"`
+	PackImages    PackImagesSettings `json:"packImages"`
+	Txt2Epub      Txt2EpubSettings   `json:"txt2epub"`
+	Gallery       GallerySettings    `json:"gallery"`
+	Concurrency   map[string]int     `json:"concurrency"`
+	RecentSources []string           `json:"recentSources"`
+	RecentTargets []string           `json:"recentTargets"`
+}
+
+type ShortcutsSettings struct {
+	TargetPath string `json:"targetPath"`
+	NamingMode string `json:"namingMode"`
+}
+
+type Convert7zSettings struct {
+	VideoOutputPath  string `json:"videoOutputPath"`
+	KeepOriginal     bool   `json:"keepOriginal"`
+	CompressionLevel int    `json:"compressionLevel"`
+}
+
+type PackImagesSettings struct {
+	TargetPath       string `json:"targetPath"`
+	CompressionLevel int    `json:"compressionLevel"`
+}
+
+type Txt2EpubSettings struct {
+	OutputPath    string `json:"outputPath"`
+	Author        string `json:"author"`
+	CustomPattern string `json:"customPattern"`
+}
+
+type GallerySettings struct {
+	OutputPath string `json:"outputPath"`
+	MaxPages   int    `json:"maxPages"`
+}
+
+func defaultSettings() Settings {
+	return Settings{
+		Version:       settingsVersion,
+		Shortcuts:     ShortcutsSettings{NamingMode: "folder"},
+		Convert7z:     Convert7zSettings{CompressionLevel: defaultCompressionLevel},
+		PackImages:    PackImagesSettings{CompressionLevel: defaultCompressionLevel},
+		Gallery:       GallerySettings{MaxPages: 50},
+		Concurrency:   map[string]int{},
+		RecentSources: []string{},
+		RecentTargets: []string{},
+	}
+}
+
+// loadSettings reads settings.json, upgrading older layouts, and applies the
+// saved concurrency limits. A missing or unreadable file means defaults.
+func (a *App) loadSettings() {
+	a.settingsMutex.Lock()
+	defer a.settingsMutex.Unlock()
+
+	a.settings = defaultSettings()
+	dir, err := appDataDir()
+	if err != nil {
+		return
+	}
+	a.settingsPath = filepath.Join(dir, "settings.json")
+
+	raw, err := os.ReadFile(a.settingsPath)
+	if err == nil {
+		if s, err := decodeSettings(raw); err == nil {
+			a.settings = s
+		}
+	}
+
+	a.tasksMutex.Lock()
+	for class, limit := range a.settings.Concurrency {
+		if _, ok := a.classLimits[class]; ok && limit >= 1 {
+			a.classLimits[class] = limit
+		}
+	}
+	a.tasksMutex.Unlock()
+}
+
+func decodeSettings(data []byte) (Settings, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Settings{}, err
+	}
+	version := 0
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+	for ; version < settingsVersion; version++ {
+		if migrate := settingsMigrations[version]; migrate != nil {
+			migrate(raw)
+		}
+	}
+	raw["version"] = settingsVersion
+
+	// Decode over the defaults so fields missing from the file keep them
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return Settings{}, err
+	}
+	s := defaultSettings()
+	if err := json.Unmarshal(upgraded, &s); err != nil {
+		return Settings{}, err
+	}
+	if s.Concurrency == nil {
+		s.Concurrency = map[string]int{}
+	}
+	return s, nil
+}
+
+// saveSettingsLocked writes the settings; caller must hold settingsMutex
+func (a *App) saveSettingsLocked() error {
+	if a.settingsPath == "" {
+		return nil // CLI runs don't touch the GUI's settings
+	}
+	data, err := json.MarshalIndent(a.settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.settingsPath, data)
+}
+
+// updateSettings applies change and saves, rolling back if the write fails
+func (a *App) updateSettings(change func(s *Settings) error) error {
+	a.settingsMutex.Lock()
+	defer a.settingsMutex.Unlock()
+
+	prev := a.settings
+	prev.Concurrency = make(map[string]int, len(a.settings.Concurrency))
+	for k, v := range a.settings.Concurrency {
+		prev.Concurrency[k] = v
+	}
+	if err := change(&a.settings); err != nil {
+		a.settings = prev
+		return err
+	}
+	if err := a.saveSettingsLocked(); err != nil {
+		a.settings = prev
+		return err
+	}
+	return nil
+}
+
+func (a *App) SettingsGet() Settings {
+	a.settingsMutex.Lock()
+	defer a.settingsMutex.Unlock()
+	s := a.settings
+	s.Concurrency = make(map[string]int, len(a.settings.Concurrency))
+	for k, v := range a.settings.Concurrency {
+		s.Concurrency[k] = v
+	}
+	s.RecentSources = append([]string{}, a.settings.RecentSources...)
+	s.RecentTargets = append([]string{}, a.settings.RecentTargets...)
+	return s
+}
+
+// SettingsReset restores the defaults, keeping the recent folder lists
+func (a *App) SettingsReset() (Settings, error) {
+	err := a.updateSettings(func(s *Settings) error {
+		d := defaultSettings()
+		d.RecentSources = s.RecentSources
+		d.RecentTargets = s.RecentTargets
+		*s = d
+		return nil
+	})
+	if err != nil {
+		return Settings{}, err
+	}
+
+	a.tasksMutex.Lock()
+	for class, limit := range defaultClassLimits() {
+		a.classLimits[class] = limit
+	}
+	a.wakeDispatcher()
+	a.tasksMutex.Unlock()
+	return a.SettingsGet(), nil
+}
+
+// ---- Per-tool defaults ----
+
+func (a *App) SettingsGetShortcuts() ShortcutsSettings {
+	return a.SettingsGet().Shortcuts
+}
+
+func (a *App) SettingsSetShortcuts(v ShortcutsSettings) error {
+	switch v.NamingMode {
+	case "", "folder", "folderOnly", "original":
+	default:
+		return fmt.Errorf("unknown namingMode: %s", v.NamingMode)
+	}
+	return a.updateSettings(func(s *Settings) error {
+		s.Shortcuts = v
+		return nil
+	})
+}
+
+func (a *App) SettingsGetConvert7z() Convert7zSettings {
+	return a.SettingsGet().Convert7z
+}
+
+func (a *App) SettingsSetConvert7z(v Convert7zSettings) error {
+	if err := checkCompressionLevel(v.CompressionLevel); err != nil {
+		return err
+	}
+	return a.updateSettings(func(s *Settings) error {
+		s.Convert7z = v
+		return nil
+	})
+}
+
+func (a *App) SettingsGetPackImages() PackImagesSettings {
+	return a.SettingsGet().PackImages
+}
+
+func (a *App) SettingsSetPackImages(v PackImagesSettings) error {
+	if err := checkCompressionLevel(v.CompressionLevel); err != nil {
+		return err
+	}
+	return a.updateSettings(func(s *Settings) error {
+		s.PackImages = v
+		return nil
+	})
+}
+
+func (a *App) SettingsGetTxt2Epub() Txt2EpubSettings {
+	return a.SettingsGet().Txt2Epub
+}
+
+func (a *App) SettingsSetTxt2Epub(v Txt2EpubSettings) error {
+	if v.CustomPattern != "" {
+		if _, err := regexp.Compile(v.CustomPattern); err != nil {
+			return fmt.Errorf("invalid customPattern: %w", err)
+		}
+	}
+	return a.updateSettings(func(s *Settings) error {
+		s.Txt2Epub = v
+		return nil
+	})
+}
+
+func (a *App) SettingsGetGallery() GallerySettings {
+	return a.SettingsGet().Gallery
+}
+
+func (a *App) SettingsSetGallery(v GallerySettings) error {
+	if v.MaxPages < 1 {
+		return fmt.Errorf("maxPages must be at least 1")
+	}
+	return a.updateSettings(func(s *Settings) error {
+		s.Gallery = v
+		return nil
+	})
+}
+
+// ---- Recent folders ----
+
+// SettingsGetRecentFolders returns the recent "source" or "target" folders, newest first
+func (a *App) SettingsGetRecentFolders(kind string) ([]string, error) {
+	s := a.SettingsGet()
+	switch kind {
+	case "source":
+		return s.RecentSources, nil
+	case "target":
+		return s.RecentTargets, nil
+	}
+	return nil, fmt.Errorf("unknown folder kind: %s", kind)
+}
+
+func (a *App) SettingsAddRecentFolder(kind string, path string) error {
+	if path == "" {
+		return nil
+	}
+	return a.updateSettings(func(s *Settings) error {
+		switch kind {
+		case "source":
+			s.RecentSources = pushRecent(s.RecentSources, path)
+		case "target":
+			s.RecentTargets = pushRecent(s.RecentTargets, path)
+		default:
+			return fmt.Errorf("unknown folder kind: %s", kind)
+		}
+		return nil
+	})
+}
+
+// pushRecent moves path to the front of list, dropping the oldest entries
+func pushRecent(list []string, path string) []string {
+	out := []string{path}
+	for _, p := range list {
+		if p != path && len(out) < maxRecentFolders {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// recentFolder returns the newest recent folder of kind, for dialog defaults
+func (a *App) recentFolder(kind string) string {
+	list, _ := a.SettingsGetRecentFolders(kind)
+	if len(list) > 0 {
+		return list[0]
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ============ Concurrency ============
+
+// Each task type draws from a resource class so that e.g. two 7z extractions
+// don't thrash the same disk while a shortcut task could run right away.
+var taskResourceClasses = map[string]string{
+	"convert-7z-to-zip":   "disk", // extraction, heavy sequential I/O
+	"pack-images":         "cpu",  // zipping
+	"convert-txt-to-epub": "cpu",
+	"crawl-gallery":       "network", // downloads
+	"create-shortcuts":    "light",
+}
+
+// defaultClassLimits is how many tasks of each class may run at once; "total" caps all classes together
+func defaultClassLimits() map[string]int {
+	return map[string]int{
+		"total":   4,
+		"disk":    1,
+		"cpu":     2,
+		"network": 1,
+		"light":   2,
+	}
+}
+
+func taskResourceClass(taskType string) string {
+	if class, ok := taskResourceClasses[taskType]; ok {
+		return class
+	}
+	return "light"
+}
+
+// TaskQueueGetConcurrency returns the limit per resource class; "total" caps all classes together
+func (a *App) TaskQueueGetConcurrency() map[string]int {
+	a.tasksMutex.Lock()
+	defer a.tasksMutex.Unlock()
+	limits := make(map[string]int, len(a.classLimits))
+	for k, v := range a.classLimits {
+		limits[k] = v
+	}
+	return limits
+}
+
+// TaskQueueSetConcurrency changes a limit at runtime. Lowering it never stops
+// running tasks, it only delays new ones.
+func (a *App) TaskQueueSetConcurrency(class string, limit int) error {
+	if limit < 1 {
+		return fmt.Errorf("limit must be at least 1")
+	}
+	a.tasksMutex.Lock()
+	if _, ok := a.classLimits[class]; !ok {
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("unknown resource class: %s", class)
+	}
+	a.classLimits[class] = limit
+	a.wakeDispatcher()
+	a.tasksMutex.Unlock()
+
+	// Remembered for the next start, see settings.go
+	return a.updateSettings(func(s *Settings) error {
+		s.Concurrency[class] = limit
+		return nil
+	})
+}
+
+// wakeDispatcher asks processTasks to look for startable tasks. Never blocks.
+func (a *App) wakeDispatcher() {
+	select {
+	case a.taskWake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchTasks starts every pending task whose resource class has a free slot,
+// in priority order.
+func (a *App) dispatchTasks() {
+	fed := a.feedReadyTasks() // task_pipeline.go
+	a.tasksMutex.Lock()
+	if a.resolveBlockedTasks() || fed {
+		go a.saveTasks()
+		a.broadcastTaskList()
+	}
+	if a.queuePaused {
+		a.tasksMutex.Unlock()
+		return
+	}
+
+	for _, t := range a.pendingTasks() {
+		if a.classRunning["total"] >= a.classLimits["total"] {
+			break
+		}
+		class := taskResourceClass(t.Type)
+		if a.classRunning[class] >= a.classLimits[class] {
+			continue
+		}
+		a.classRunning[class]++
+		a.classRunning["total"]++
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Status = "running"
+		t.StartedAt = time.Now().UnixMilli()
+		t.FinishedAt = 0
+		t.cancel = cancel
+		t.running = true
+		go a.runTask(ctx, t)
+	}
+	a.tasksMutex.Unlock()
+}
+
+// releaseSlot frees the class slot of a finished task and lets the next one start
+func (a *App) releaseSlot(class string) {
+	a.tasksMutex.Lock()
+	a.classRunning[class]--
+	a.classRunning["total"]--
+	a.wakeDispatcher()
+	a.tasksMutex.Unlock()
+}
@@ -1,76 +1,57 @@
 package main
 
 import (
-	"archive/zip"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
-	"strings"
+
+	"wcs-toolbox/pkg/archive"
+	"wcs-toolbox/pkg/shortcut"
 )
 
 // ============ Handlers ============
 
 func (a *App) handleCreateShortcuts(ctx context.Context, task *Task) (interface{}, error) {
-	// Manual casting from map because Wails unmarshals interface{} as map[string]interface{}
-	dataMap, ok := task.Data.(map[string]interface{})
+	// Decoded and validated by TaskQueueAdd (task_params.go)
+	params, ok := task.Data.(*CreateShortcutsParams)
 	if !ok {
 		return nil, fmt.Errorf("invalid data format")
 	}
-
-	targetPath := dataMap["targetPath"].(string)
-	namingMode := dataMap["namingMode"].(string)
-
-	videosListRaw, ok := dataMap["videos"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid videos list")
-	}
+	targetPath := params.TargetPath
+	namingMode := params.NamingMode
 
 	success := 0
 	failed := 0
 	var errors []ErrorDetail
-	total := len(videosListRaw)
+	total := len(params.Videos)
+	done := a.completedItems(task)
 
-	for i, v := range videosListRaw {
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
+	for i, v := range params.Videos {
+		if err := a.waitIfPaused(ctx, task); err != nil {
+			return nil, err
 		}
 
-		videoMap := v.(map[string]interface{})
-		videoPath := videoMap["path"].(string)
-		videoName := videoMap["name"].(string)
-		parentFolder := videoMap["parentFolder"].(string)
-
-		var linkName string
-		ext := filepath.Ext(videoName)
-
-		switch namingMode {
-		case "folder":
-			linkName = fmt.Sprintf("%s_%s", parentFolder, videoName)
-		case "folderOnly":
-			linkName = fmt.Sprintf("%s%s", parentFolder, ext)
-			// Handle dupe
-			counter := 1
-			for {
-				if _, err := os.Stat(filepath.Join(targetPath, linkName)); os.IsNotExist(err) {
-					break
-				}
-				linkName = fmt.Sprintf("%s_%d%s", parentFolder, counter, ext)
-				counter++
-			}
-		default:
-			linkName = videoName
+		videoPath := v.Path
+		videoName := v.Name
+		parentFolder := v.ParentFolder
+
+		if done[videoPath] {
+			success++
+			continue
 		}
 
-		linkPath := filepath.Join(targetPath, linkName)
-		err := a.createLink(videoPath, linkPath)
+		linkName := shortcut.LinkName(namingMode, targetPath, parentFolder, videoName)
+		linkPath, err := shortcut.Create(videoPath, filepath.Join(targetPath, linkName))
 		if err != nil {
+			a.taskLogf(task, "error", "创建快捷方式失败 %s: %v", videoName, err)
 			failed++
-			errors = append(errors, ErrorDetail{File: videoName, Error: err.Error()})
+			errors = append(errors, ErrorDetail{File: videoName, Path: videoPath, Error: err.Error()})
 		} else {
 			success++
+			a.markItemDone(task, videoPath)
+			a.addTaskOutput(task, linkPath)
+			a.taskLogf(task, "info", "%s -> %s", videoPath, linkPath)
 		}
 
 		// Update progress
@@ -80,119 +61,83 @@ func (a *App) handleCreateShortcuts(ctx context.Context, task *Task) (interface{
 	return TaskResult{Success: success, Failed: failed, Errors: errors}, nil
 }
 
-func (a *App) createLink(src, dst string) error {
-	os.MkdirAll(filepath.Dir(dst), 0755)
-	if runtime.GOOS == "windows" {
-		if !strings.HasSuffix(strings.ToLower(dst), ".lnk") {
-			dst += ".lnk"
-		}
-		// Powershell shortcut creation
-		psScript := fmt.Sprintf("$s=(New-Object -COM WScript.Shell).CreateShortcut('%s');$s.TargetPath='%s';$s.Save()", dst, src)
-		cmd := exec.Command("powershell", "-Command", psScript)
-		return cmd.Run()
+func (a *App) handleConvertArchive(ctx context.Context, task *Task) (interface{}, error) {
+	params, ok := task.Data.(*Convert7zParams)
+	if !ok {
+		return nil, fmt.Errorf("invalid data format")
 	}
-	return os.Symlink(src, dst)
-}
-
-func (a *App) handleConvert7z(ctx context.Context, task *Task) (interface{}, error) {
-	dataMap, _ := task.Data.(map[string]interface{})
-	filesListRaw, _ := dataMap["files"].([]interface{})
-	videoOut := dataMap["videoOutputPath"].(string)
+	videoOut := params.VideoOutputPath
 
 	// Ensure video output dir exists
 	os.MkdirAll(videoOut, 0755)
 
-	total := len(filesListRaw)
+	total := len(params.Files)
 	success := 0
 	failed := 0
 	var errors []ErrorDetail
+	done := a.completedItems(task)
 
-	for i, f := range filesListRaw {
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
-		}
-		fMap := f.(map[string]interface{})
-		path7z := fMap["path"].(string)
-		name := fMap["name"].(string)
+	var totalBytes int64
+	for _, f := range params.Files {
+		totalBytes += f.Size
+	}
+	a.setTaskBytesTotal(task, totalBytes)
+	passwords := a.archivePasswords(task.passwords)
 
-		// Create temp dir
-		tempDir, err := os.MkdirTemp("", "wcs_extract_")
-		if err != nil {
-			failed++
-			errors = append(errors, ErrorDetail{File: name, Error: "Temp dir error"})
-			continue
+	for i, f := range params.Files {
+		if err := a.waitIfPaused(ctx, task); err != nil {
+			return nil, err
 		}
+		src := f.Path
+		name := f.Name
 
-		// Run 7z
-		// We use "7z" command. If not in path, this fails.
-		// TODO: Bundling 7z is better but out of scope for simple migration without binary resources.
-		cmd := exec.Command("7z", "x", path7z, "-o"+tempDir, "-y")
-		if err := cmd.Run(); err != nil {
-			// Try "7za" (often on Mac/Linux)
-			cmd = exec.Command("7za", "x", path7z, "-o"+tempDir, "-y")
-			if err := cmd.Run(); err != nil {
-				os.RemoveAll(tempDir)
-				failed++
-				errors = append(errors, ErrorDetail{File: name, Error: "7z extract failed: " + err.Error()})
-				continue
-			}
+		if done[src] {
+			a.addTaskBytes(task, f.Size)
+			success++
+			continue
 		}
 
-		// Scan and Move/Zip
-		var filesToZip []string
-
-		err = filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil
-			}
-			if !info.IsDir() {
-				ext := strings.ToLower(filepath.Ext(path))
-				isVideo := false
-				for _, ve := range []string{".mp4", ".mkv", ".avi", ".wmv", ".mov", ".flv", ".webm", ".ts"} {
-					if ext == ve {
-						isVideo = true
-						break
-					}
-				}
-
-				if isVideo {
-					// Move to videoOut
-					destName := info.Name()
-					destPath := filepath.Join(videoOut, destName)
-
-					// Handle duplicate
-					counter := 1
-					for {
-						if _, err := os.Stat(destPath); os.IsNotExist(err) {
-							break
-						}
-						destPath = filepath.Join(videoOut, fmt.Sprintf("%s_%d%s", strings.TrimSuffix(destName, ext), counter, ext))
-						counter++
-					}
-
-					os.Rename(path, destPath)
-				} else {
-					filesToZip = append(filesToZip, path)
+		out, err := archive.ConvertToZip(ctx, src, videoOut, passwords, compressionLevel(params.CompressionLevel), taskReporter{a, task})
+		// The original only goes once the zip reads back intact and the videos are all there
+		remove := err == nil && !params.KeepOriginal
+		if remove {
+			if out.Zip == "" && len(out.Videos) == 0 {
+				a.taskLogf(task, "warn", "%s 中没有可校验的文件，保留原文件", name)
+				remove = false
+			} else {
+				a.taskLogf(task, "info", "校验 %s 的输出", name)
+				if verr := archive.VerifyConverted(ctx, out); verr != nil {
+					// Drop the outputs too, so a retry starts clean instead of next to them
+					removeConverted(out)
+					err = fmt.Errorf("verification failed, original kept: %w", verr)
 				}
 			}
-			return nil
-		})
-
-		// Create Zip
-		if len(filesToZip) > 0 {
-			zipPath := strings.TrimSuffix(path7z, filepath.Ext(path7z)) + ".zip"
-			err = zipFiles(zipPath, filesToZip, tempDir)
-			if err != nil {
-				errors = append(errors, ErrorDetail{File: name, Error: "Zip failed: " + err.Error()})
-				// But we count as success if extraction worked? No, partial failure.
+		}
+		if err != nil {
+			a.taskLogf(task, "error", "转换失败 %s: %v", name, err)
+			if needsPassword(err) {
+				a.taskLogf(task, "warn", "%s 需要密码，可在任务列表中点击“输入密码重试”", name)
 			}
-			success++
+			failed++
+			errors = append(errors, ErrorDetail{File: name, Path: src, Error: err.Error(), NeedsPassword: needsPassword(err)})
 		} else {
-			// Only videos? Success.
 			success++
+			a.markItemDone(task, src)
+			for _, v := range out.Videos {
+				a.addTaskOutput(task, v)
+			}
+			if out.Password != "" {
+				a.taskLogf(task, "info", "%s 已加密，使用%s 解压成功", name, a.passwordSource(out.Password, task.passwords))
+			}
+			if out.Zip != "" {
+				a.addTaskOutput(task, out.Zip)
+				a.taskLogf(task, "info", "已生成 %s (%d 个文件)", out.Zip, out.Files)
+			}
+			if remove {
+				a.removeOriginal(task, src)
+			}
 		}
 
-		os.RemoveAll(tempDir)
 		a.updateTaskProgress(task, i+1, total)
 	}
 
@@ -200,53 +145,52 @@ func (a *App) handleConvert7z(ctx context.Context, task *Task) (interface{}, err
 }
 
 func (a *App) handlePackImages(ctx context.Context, task *Task) (interface{}, error) {
-	dataMap, _ := task.Data.(map[string]interface{})
-	foldersListRaw, _ := dataMap["folders"].([]interface{})
-	targetPath := dataMap["targetPath"].(string)
+	params, ok := task.Data.(*PackImagesParams)
+	if !ok {
+		return nil, fmt.Errorf("invalid data format")
+	}
+	targetPath := params.TargetPath
 
 	os.MkdirAll(targetPath, 0755)
 
 	success := 0
 	failed := 0
 	var errors []ErrorDetail
-	total := len(foldersListRaw)
+	total := len(params.Folders)
+	done := a.completedItems(task)
 
-	for i, folder := range foldersListRaw {
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
+	var totalBytes int64
+	for _, folder := range params.Folders {
+		totalBytes += folder.TotalSize
+	}
+	a.setTaskBytesTotal(task, totalBytes)
+
+	for i, folder := range params.Folders {
+		if err := a.waitIfPaused(ctx, task); err != nil {
+			return nil, err
+		}
+		folderPath := folder.Path
+		folderName := folder.Name
+
+		if done[folderPath] {
+			a.addTaskBytes(task, folder.TotalSize)
+			success++
+			continue
 		}
-		fMap := folder.(map[string]interface{})
-		folderPath := fMap["path"].(string)
-		folderName := fMap["name"].(string)
 
 		zipName := folderName + ".zip"
 		dest := filepath.Join(targetPath, zipName)
 
-		// Collect images
-		var images []string
-		filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
-			if !info.IsDir() {
-				ext := strings.ToLower(filepath.Ext(path))
-				// Simple check
-				if strings.Contains(".jpg.jpeg.png.gif.bmp.webp", ext) {
-					images = append(images, path)
-				}
-			}
-			return nil
-		})
-
-		if len(images) > 0 {
-			err := zipFiles(dest, images, folderPath)
-			if err != nil {
-				failed++
-				errors = append(errors, ErrorDetail{File: folderName, Error: err.Error()})
-			} else {
-				success++
-			}
-		} else {
-			// No images -> skip or consider success?
+		count, err := archive.PackImages(folderPath, dest, compressionLevel(params.CompressionLevel), taskReporter{a, task})
+		if err != nil {
+			a.taskLogf(task, "error", "打包失败 %s: %v", folderName, err)
 			failed++
-			errors = append(errors, ErrorDetail{File: folderName, Error: "No images found"})
+			errors = append(errors, ErrorDetail{File: folderName, Path: folderPath, Error: err.Error()})
+		} else {
+			success++
+			a.markItemDone(task, folderPath)
+			a.addTaskOutput(task, dest)
+			a.taskLogf(task, "info", "已生成 %s (%d 张图片)", dest, count)
 		}
 
 		a.updateTaskProgress(task, i+1, total)
@@ -255,43 +199,62 @@ func (a *App) handlePackImages(ctx context.Context, task *Task) (interface{}, er
 	return TaskResult{Success: success, Failed: failed, Errors: errors}, nil
 }
 
-func (a *App) updateTaskProgress(task *Task, current, total int) {
-	a.tasksMutex.Lock()
-	task.Progress = int(float64(current) / float64(total) * 100)
-	a.tasksMutex.Unlock()
-	a.broadcastTaskUpdate(task)
+// removeConverted deletes what ConvertToZip wrote for an archive
+func removeConverted(out archive.Converted) {
+	if out.Zip != "" {
+		os.Remove(out.Zip)
+	}
+	for _, v := range out.Videos {
+		os.Remove(v)
+	}
 }
 
-func zipFiles(dest string, files []string, baseDir string) error {
-	f, err := os.Create(dest)
-	if err != nil {
-		return err
+// removeOriginal moves every volume of a converted archive to the trash. A
+// failure here doesn't fail the item: the original just stays.
+func (a *App) removeOriginal(task *Task, src string) {
+	for _, v := range archive.Volumes(src) {
+		if err := moveToTrash(v); err != nil {
+			a.taskLogf(task, "warn", "无法移到回收站，已保留原文件 %s: %v", v, err)
+			continue
+		}
+		a.taskLogf(task, "info", "原文件已移到回收站 %s", v)
 	}
-	defer f.Close()
-
-	w := zip.NewWriter(f)
-	defer w.Close()
+}
 
-	for _, file := range files {
-		rel, err := filepath.Rel(baseDir, file)
-		if err != nil {
-			rel = filepath.Base(file)
-		} // Fallback
+// completedItems returns the items a previous run of this task already finished
+func (a *App) completedItems(task *Task) map[string]bool {
+	a.tasksMutex.Lock()
+	defer a.tasksMutex.Unlock()
+	done := make(map[string]bool, len(task.Completed))
+	for _, key := range task.Completed {
+		done[key] = true
+	}
+	return done
+}
 
-		zf, err := w.Create(rel)
-		if err != nil {
-			return err
-		}
+// markItemDone checkpoints a finished item so a resumed task can skip it
+func (a *App) markItemDone(task *Task, key string) {
+	a.tasksMutex.Lock()
+	task.Completed = append(task.Completed, key)
+	a.scheduleSave()
+	a.tasksMutex.Unlock()
+}
 
-		c, err := os.ReadFile(file)
-		if err != nil {
-			return err
-		}
+// addTaskOutput records a produced file so pipelines can feed it to the next task
+func (a *App) addTaskOutput(task *Task, path string) {
+	a.tasksMutex.Lock()
+	task.Outputs = append(task.Outputs, path)
+	a.scheduleSave()
+	a.tasksMutex.Unlock()
+}
 
-		_, err = zf.Write(c)
-		if err != nil {
-			return err
-		}
+func (a *App) updateTaskProgress(task *Task, current, total int) {
+	a.tasksMutex.Lock()
+	if task.BytesTotal == 0 {
+		// Byte-level progress (task_progress.go) is finer when available
+		task.Progress = int(float64(current) / float64(total) * 100)
 	}
-	return nil
+	a.scheduleSave()
+	a.tasksMutex.Unlock()
+	a.broadcastTaskUpdate(task)
 }
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	wruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============ Task History ============
+
+// HistoryRecord is the archived form of a finished task. Data holds the task's
+// options without items; the items are listed in Items.
+type HistoryRecord struct {
+	TaskID     int           `json:"taskId"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Status     string        `json:"status"`
+	Data       interface{}   `json:"data,omitempty"`
+	Items      []string      `json:"items,omitempty"`
+	Outputs    []string      `json:"outputs,omitempty"`
+	CreatedAt  int64         `json:"createdAt"`
+	StartedAt  int64         `json:"startedAt,omitempty"`
+	FinishedAt int64         `json:"finishedAt"`
+	Duration   int64         `json:"duration"` // ms from start to finish, 0 if it never ran
+	Success    int           `json:"success"`
+	Failed     int           `json:"failed"`
+	Error      string        `json:"error,omitempty"`
+	Errors     []ErrorDetail `json:"errors,omitempty"`
+}
+
+// HistoryQuery filters the history; zero values match everything. From and To
+// are unix ms compared with FinishedAt.
+type HistoryQuery struct {
+	From   int64  `json:"from"`
+	To     int64  `json:"to"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	File   string `json:"file"` // case-insensitive substring of a name, item, output or failed file
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"` // 0 means no limit
+}
+
+type HistoryPage struct {
+	Records []HistoryRecord `json:"records"`
+	Total   int             `json:"total"` // matches before Offset/Limit
+}
+
+// key tells records apart even if the task journal was reset and ids restarted.
+// Each run of a resumed task is its own record, so StartedAt is part of it.
+func (r HistoryRecord) key() string {
+	return fmt.Sprintf("%d-%d-%d", r.TaskID, r.CreatedAt, r.StartedAt)
+}
+
+// loadHistory reads history.jsonl, one record per line, appended as tasks finish
+func (a *App) loadHistory() {
+	a.historyMutex.Lock()
+	defer a.historyMutex.Unlock()
+
+	a.history = nil
+	a.historyKeys = make(map[string]bool)
+	dir, err := appDataDir()
+	if err != nil {
+		return
+	}
+	a.historyPath = filepath.Join(dir, "history.jsonl")
+
+	f, err := os.Open(a.historyPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		var r HistoryRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			continue // a line cut short by a crash
+		}
+		if !a.historyKeys[r.key()] {
+			a.historyKeys[r.key()] = true
+			a.history = append(a.history, r)
+		}
+	}
+}
+
+// newHistoryRecord copies what the history keeps of task; caller must hold tasksMutex
+func newHistoryRecord(t *Task) HistoryRecord {
+	r := HistoryRecord{
+		TaskID:     t.ID,
+		Type:       t.Type,
+		Name:       t.Name,
+		Status:     t.Status,
+		Outputs:    append([]string(nil), t.Outputs...),
+		CreatedAt:  t.CreatedAt,
+		StartedAt:  t.StartedAt,
+		FinishedAt: t.FinishedAt,
+		Error:      t.Error,
+	}
+	if r.FinishedAt == 0 {
+		r.FinishedAt = time.Now().UnixMilli()
+	}
+	if r.StartedAt > 0 {
+		r.Duration = r.FinishedAt - r.StartedAt
+	}
+	if params, ok := t.Data.(taskParams); ok {
+		r.Items = params.itemKeys()
+		r.Data = params.withItems(func(string) bool { return false })
+	} else {
+		r.Data = t.Data
+	}
+	summary := taskResultSummary(t.Result)
+	r.Success, r.Failed, r.Errors = summary.Success, summary.Failed, summary.Errors
+	return r
+}
+
+// archiveTasks appends the records not archived yet
+func (a *App) archiveTasks(records []HistoryRecord) {
+	a.historyMutex.Lock()
+	defer a.historyMutex.Unlock()
+	if a.historyKeys == nil {
+		a.historyKeys = make(map[string]bool)
+	}
+
+	var lines []byte
+	for _, r := range records {
+		if a.historyKeys[r.key()] {
+			continue
+		}
+		raw, err := json.Marshal(r)
+		if err != nil {
+			continue
+		}
+		a.historyKeys[r.key()] = true
+		a.history = append(a.history, r)
+		lines = append(append(lines, raw...), '\n')
+	}
+	if len(lines) == 0 || a.historyPath == "" {
+		return
+	}
+	f, err := os.OpenFile(a.historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(lines)
+}
+
+func (q HistoryQuery) match(r HistoryRecord) bool {
+	if q.From > 0 && r.FinishedAt < q.From {
+		return false
+	}
+	if q.To > 0 && r.FinishedAt > q.To {
+		return false
+	}
+	if q.Type != "" && r.Type != q.Type {
+		return false
+	}
+	if q.Status != "" && r.Status != q.Status {
+		return false
+	}
+	if q.File == "" {
+		return true
+	}
+	needle := strings.ToLower(q.File)
+	contains := func(s string) bool { return strings.Contains(strings.ToLower(s), needle) }
+	if contains(r.Name) {
+		return true
+	}
+	for _, list := range [][]string{r.Items, r.Outputs} {
+		for _, s := range list {
+			if contains(s) {
+				return true
+			}
+		}
+	}
+	for _, e := range r.Errors {
+		if contains(e.File) || contains(e.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryQuery returns matching records, newest first
+func (a *App) HistoryQuery(q HistoryQuery) HistoryPage {
+	a.historyMutex.Lock()
+	var matched []HistoryRecord
+	for _, r := range a.history {
+		if q.match(r) {
+			matched = append(matched, r)
+		}
+	}
+	a.historyMutex.Unlock()
+
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].FinishedAt > matched[j].FinishedAt })
+	page := HistoryPage{Records: []HistoryRecord{}, Total: len(matched)}
+	if q.Offset < len(matched) {
+		matched = matched[max(q.Offset, 0):]
+		if q.Limit > 0 && q.Limit < len(matched) {
+			matched = matched[:q.Limit]
+		}
+		page.Records = matched
+	}
+	return page
+}
+
+// writeHistory writes records as "json" (an array) or "csv"
+func writeHistory(w io.Writer, records []HistoryRecord, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"taskId", "type", "name", "status", "createdAt", "startedAt", "finishedAt", "durationMs", "success", "failed", "error", "items", "outputs", "errors"})
+		for _, r := range records {
+			var errs []string
+			for _, e := range r.Errors {
+				errs = append(errs, fmt.Sprintf("%s: %s", e.File+e.Gallery, e.Error))
+			}
+			cw.Write([]string{
+				strconv.Itoa(r.TaskID), r.Type, r.Name, r.Status,
+				historyTime(r.CreatedAt), historyTime(r.StartedAt), historyTime(r.FinishedAt),
+				strconv.FormatInt(r.Duration, 10), strconv.Itoa(r.Success), strconv.Itoa(r.Failed), r.Error,
+				strings.Join(r.Items, ";"), strings.Join(r.Outputs, ";"), strings.Join(errs, "; "),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown export format: %s", format)
+}
+
+func historyTime(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).Format(time.RFC3339)
+}
+
+// HistoryExport writes every record matching q (ignoring Offset/Limit) to path
+func (a *App) HistoryExport(q HistoryQuery, format string, path string) (int, error) {
+	q.Offset, q.Limit = 0, 0
+	records := a.HistoryQuery(q).Records
+
+	var buf strings.Builder
+	if format == "csv" {
+		buf.WriteString("\ufeff") // so Excel opens Chinese names as UTF-8
+	}
+	if err := writeHistory(&buf, records, format); err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, []byte(buf.String()), 0644); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+// HistoryExportDialog asks where to save the export; 0 records and no error
+// means the dialog was cancelled
+func (a *App) HistoryExportDialog(q HistoryQuery, format string) (int, error) {
+	path, err := wruntime.SaveFileDialog(a.ctx, wruntime.SaveDialogOptions{
+		Title:           "导出任务历史",
+		DefaultFilename: "task-history." + format,
+		Filters: []wruntime.FileFilter{
+			{DisplayName: strings.ToUpper(format), Pattern: "*." + format},
+		},
+	})
+	if err != nil || path == "" {
+		return 0, err
+	}
+	return a.HistoryExport(q, format, path)
+}
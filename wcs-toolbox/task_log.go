@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ============ Task Logs ============
+
+// Older entries are dropped once a task has this many
+const maxTaskLogEntries = 500
+
+type TaskLogEntry struct {
+	Seq     int    `json:"seq"`
+	Time    int64  `json:"time"`
+	Level   string `json:"level"` // info, warn, error
+	Message string `json:"message"`
+}
+
+// taskLogf appends to the task's log and streams the entry on "task-log"
+func (a *App) taskLogf(task *Task, level string, format string, args ...interface{}) {
+	a.tasksMutex.Lock()
+	seq := 1
+	if n := len(task.Logs); n > 0 {
+		seq = task.Logs[n-1].Seq + 1
+	}
+	entry := TaskLogEntry{
+		Seq:     seq,
+		Time:    time.Now().UnixMilli(),
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+	}
+	task.Logs = append(task.Logs, entry)
+	if len(task.Logs) > maxTaskLogEntries {
+		task.Logs = append([]TaskLogEntry(nil), task.Logs[len(task.Logs)-maxTaskLogEntries:]...)
+	}
+	id := task.ID
+	a.scheduleSave()
+	a.tasksMutex.Unlock()
+
+	a.emitEvent("task-log", map[string]interface{}{
+		"taskId": id,
+		"entry":  entry,
+	})
+}
+
+// TaskQueueGetLogs returns log entries with Seq greater than afterSeq (0 for all)
+func (a *App) TaskQueueGetLogs(id int, afterSeq int) ([]TaskLogEntry, error) {
+	a.tasksMutex.Lock()
+	defer a.tasksMutex.Unlock()
+	task, ok := a.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task %d not found", id)
+	}
+	entries := []TaskLogEntry{}
+	for _, e := range task.Logs {
+		if e.Seq > afterSeq {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// ============ Task Params ============
+
+// taskParams is implemented by the *Params structs in app.go. Task.Data always
+// holds one of them once a task is in the queue, so handlers never see raw maps.
+type taskParams interface {
+	// validate checks options; an empty item list is allowed here because
+	// pipeline steps get their items later
+	validate() error
+	// itemKeys returns the path (URL for galleries) of every item, in order
+	itemKeys() []string
+	// withItems returns a copy keeping only the items for which keep is true
+	withItems(keep func(key string) bool) taskParams
+}
+
+// decodeTaskParams turns data as sent by the frontend (or read back from the
+// journal) into the params struct for taskType. Missing or mistyped fields
+// become an error here instead of a panic inside the handler.
+func decodeTaskParams(taskType string, data interface{}) (taskParams, error) {
+	var params taskParams
+	switch taskType {
+	case "create-shortcuts":
+		params = &CreateShortcutsParams{}
+	case "convert-7z-to-zip":
+		params = &Convert7zParams{}
+	case "pack-images":
+		params = &PackImagesParams{}
+	case "convert-txt-to-epub":
+		params = &ConvertTxtParams{}
+	case "crawl-gallery":
+		params = &CrawlGalleryParams{}
+	default:
+		return nil, fmt.Errorf("unknown task type: %s", taskType)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data for %s: %v", taskType, err)
+	}
+	if err := json.Unmarshal(raw, params); err != nil {
+		return nil, fmt.Errorf("invalid data for %s: %v", taskType, err)
+	}
+	if err := params.validate(); err != nil {
+		return nil, fmt.Errorf("invalid data for %s: %v", taskType, err)
+	}
+	return params, nil
+}
+
+func requirePath(field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", field)
+	}
+	return nil
+}
+
+// defaultCompressionLevel is what settings start with and what the CLI and
+// API/preset params that leave the level out get; 0 would mean Store
+const defaultCompressionLevel = 1
+
+func checkCompressionLevel(level int) error {
+	if level < 0 || level > 9 {
+		return fmt.Errorf("compressionLevel must be between 0 and 9, got %d", level)
+	}
+	return nil
+}
+
+// compressionLevel resolves an optional level from task params
+func compressionLevel(level *int) int {
+	if level == nil {
+		return defaultCompressionLevel
+	}
+	return *level
+}
+
+// ---- create-shortcuts ----
+
+func (p *CreateShortcutsParams) validate() error {
+	if err := requirePath("targetPath", p.TargetPath); err != nil {
+		return err
+	}
+	switch p.NamingMode {
+	case "", "folder", "folderOnly", "original":
+	default:
+		return fmt.Errorf("unknown namingMode: %s", p.NamingMode)
+	}
+	for i, v := range p.Videos {
+		if v.Path == "" || v.Name == "" {
+			return fmt.Errorf("videos[%d]: path and name are required", i)
+		}
+	}
+	return nil
+}
+
+func (p *CreateShortcutsParams) itemKeys() []string {
+	keys := make([]string, len(p.Videos))
+	for i, v := range p.Videos {
+		keys[i] = v.Path
+	}
+	return keys
+}
+
+func (p *CreateShortcutsParams) withItems(keep func(string) bool) taskParams {
+	c := *p
+	c.Videos = nil
+	for _, v := range p.Videos {
+		if keep(v.Path) {
+			c.Videos = append(c.Videos, v)
+		}
+	}
+	return &c
+}
+
+// ---- convert-7z-to-zip ----
+
+func (p *Convert7zParams) validate() error {
+	if err := requirePath("videoOutputPath", p.VideoOutputPath); err != nil {
+		return err
+	}
+	if err := checkCompressionLevel(compressionLevel(p.CompressionLevel)); err != nil {
+		return err
+	}
+	return checkFiles(p.Files)
+}
+
+func (p *Convert7zParams) itemKeys() []string {
+	return fileKeys(p.Files)
+}
+
+func (p *Convert7zParams) withItems(keep func(string) bool) taskParams {
+	c := *p
+	c.Files = filterFiles(p.Files, keep)
+	return &c
+}
+
+// ---- pack-images ----
+
+func (p *PackImagesParams) validate() error {
+	if err := requirePath("targetPath", p.TargetPath); err != nil {
+		return err
+	}
+	if err := checkCompressionLevel(compressionLevel(p.CompressionLevel)); err != nil {
+		return err
+	}
+	for i, f := range p.Folders {
+		if f.Path == "" || f.Name == "" {
+			return fmt.Errorf("folders[%d]: path and name are required", i)
+		}
+	}
+	return nil
+}
+
+func (p *PackImagesParams) itemKeys() []string {
+	keys := make([]string, len(p.Folders))
+	for i, f := range p.Folders {
+		keys[i] = f.Path
+	}
+	return keys
+}
+
+func (p *PackImagesParams) withItems(keep func(string) bool) taskParams {
+	c := *p
+	c.Folders = nil
+	for _, f := range p.Folders {
+		if keep(f.Path) {
+			c.Folders = append(c.Folders, f)
+		}
+	}
+	return &c
+}
+
+// ---- convert-txt-to-epub ----
+
+func (p *ConvertTxtParams) validate() error {
+	if err := requirePath("outputPath", p.OutputPath); err != nil {
+		return err
+	}
+	if p.Options.CustomPattern != "" {
+		if _, err := regexp.Compile(p.Options.CustomPattern); err != nil {
+			return fmt.Errorf("invalid customPattern: %v", err)
+		}
+	}
+	return checkFiles(p.Files)
+}
+
+func (p *ConvertTxtParams) itemKeys() []string {
+	return fileKeys(p.Files)
+}
+
+func (p *ConvertTxtParams) withItems(keep func(string) bool) taskParams {
+	c := *p
+	c.Files = filterFiles(p.Files, keep)
+	return &c
+}
+
+// ---- crawl-gallery ----
+
+func (p *CrawlGalleryParams) validate() error {
+	if err := requirePath("outputPath", p.OutputPath); err != nil {
+		return err
+	}
+	for i, g := range p.Galleries {
+		if g.URL == "" {
+			return fmt.Errorf("galleries[%d]: url is required", i)
+		}
+	}
+	return nil
+}
+
+func (p *CrawlGalleryParams) itemKeys() []string {
+	keys := make([]string, len(p.Galleries))
+	for i, g := range p.Galleries {
+		keys[i] = g.URL
+	}
+	return keys
+}
+
+func (p *CrawlGalleryParams) withItems(keep func(string) bool) taskParams {
+	c := *p
+	c.Galleries = nil
+	for _, g := range p.Galleries {
+		if keep(g.URL) {
+			c.Galleries = append(c.Galleries, g)
+		}
+	}
+	return &c
+}
+
+// ---- shared ----
+
+func checkFiles(files []FileInfo) error {
+	for i, f := range files {
+		if f.Path == "" || f.Name == "" {
+			return fmt.Errorf("files[%d]: path and name are required", i)
+		}
+	}
+	return nil
+}
+
+func fileKeys(files []FileInfo) []string {
+	keys := make([]string, len(files))
+	for i, f := range files {
+		keys[i] = f.Path
+	}
+	return keys
+}
+
+func filterFiles(files []FileInfo, keep func(string) bool) []FileInfo {
+	var out []FileInfo
+	for _, f := range files {
+		if keep(f.Path) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
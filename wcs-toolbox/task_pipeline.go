@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wcs-toolbox/pkg/scanner"
+)
+
+// ============ Dependencies & Pipelines ============
+
+type PipelineStep struct {
+	Type string      `json:"type"`
+	Name string      `json:"name"`
+	Data interface{} `json:"data"` // same params as TaskQueueAdd; the item list of later steps is filled from the previous step's outputs
+}
+
+// TaskQueueAddAfter adds a task that stays blocked until every task in dependsOn
+// has completed. If any of them fails or is cancelled, this task is cancelled.
+func (a *App) TaskQueueAddAfter(taskType string, data interface{}, name string, dependsOn []int) (int, error) {
+	params, err := decodeTaskParams(taskType, data)
+	if err != nil {
+		return 0, err
+	}
+	if len(params.itemKeys()) == 0 {
+		return 0, fmt.Errorf("no items to process")
+	}
+	if err := a.checkDependencies(dependsOn); err != nil {
+		return 0, err
+	}
+	return a.addTask(&Task{
+		Type:      taskType,
+		Name:      name,
+		Data:      params,
+		DependsOn: dependsOn,
+	}), nil
+}
+
+// TaskQueueAddPipeline chains steps so that each one starts after the previous
+// completes and processes the files it produced, e.g. 7z→zip then shortcuts
+// for the extracted videos.
+func (a *App) TaskQueueAddPipeline(steps []PipelineStep) ([]int, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("pipeline has no steps")
+	}
+	// Validate every step before enqueueing any of them
+	decoded := make([]taskParams, len(steps))
+	for i, step := range steps {
+		params, err := decodeTaskParams(step.Type, step.Data)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %v", i+1, err)
+		}
+		if i == 0 && len(params.itemKeys()) == 0 {
+			return nil, fmt.Errorf("step 1: no items to process")
+		}
+		if i > 0 && step.Type == "crawl-gallery" {
+			return nil, fmt.Errorf("step %d: task type %s cannot take files from a previous step", i+1, step.Type)
+		}
+		if i > 0 && step.Type == "convert-7z-to-zip" {
+			// Every task type outputs zips, videos, epubs or shortcuts, none of them 7z/rar/tar
+			return nil, fmt.Errorf("step %d: no task type outputs archives %s can read, it can only be the first step", i+1, step.Type)
+		}
+		decoded[i] = params
+	}
+
+	var ids []int
+	for i, step := range steps {
+		task := &Task{Type: step.Type, Name: step.Name, Data: decoded[i]}
+		if i > 0 {
+			prev := ids[i-1]
+			task.DependsOn = []int{prev}
+			task.FeedFrom = prev
+		}
+		ids = append(ids, a.addTask(task))
+	}
+	return ids, nil
+}
+
+func (a *App) checkDependencies(dependsOn []int) error {
+	a.tasksMutex.Lock()
+	defer a.tasksMutex.Unlock()
+	for _, dep := range dependsOn {
+		if _, ok := a.tasks[dep]; !ok {
+			return fmt.Errorf("prerequisite task %d not found", dep)
+		}
+	}
+	return nil
+}
+
+// resolveBlockedTasks releases blocked tasks whose prerequisites completed and
+// cancels those with a failed one. Repeats until stable so cancellations cascade
+// down a chain. Reports whether any task changed. Caller must hold tasksMutex.
+func (a *App) resolveBlockedTasks() bool {
+	resolved := false
+	for changed := true; changed; {
+		changed = false
+		for _, t := range a.tasks {
+			if t.Status != "blocked" {
+				continue
+			}
+			ready := true
+			for _, dep := range t.DependsOn {
+				d, ok := a.tasks[dep]
+				switch {
+				case !ok || d.Status == "failed" || d.Status == "cancelled":
+					t.Status = "cancelled"
+					t.Error = fmt.Sprintf("前置任务 #%d 未成功完成", dep)
+					changed = true
+				case d.Status != "completed":
+					ready = false
+				}
+				if t.Status != "blocked" {
+					break
+				}
+			}
+			if t.Status != "blocked" || !ready {
+				continue
+			}
+
+			if t.FeedFrom != 0 {
+				// Left blocked for feedReadyTasks, which stats files outside the lock
+				a.wakeDispatcher()
+				continue
+			}
+			changed = true
+			t.Status = "pending"
+		}
+		resolved = resolved || changed
+	}
+	return resolved
+}
+
+// feedReadyTasks fills the item lists of blocked tasks whose prerequisites
+// completed from the outputs of t.FeedFrom, and makes them pending (or failed
+// when nothing fits). The outputs are copied under tasksMutex but looked at
+// after releasing it, so a slow drive doesn't hold up the queue and the UI.
+// Reports whether any task changed.
+func (a *App) feedReadyTasks() bool {
+	type feed struct {
+		task    *Task
+		data    interface{}
+		from    int
+		outputs []string
+		gone    bool // the feeding task was cleared from the queue
+	}
+	var feeds []feed
+	a.tasksMutex.Lock()
+	for _, t := range a.tasks {
+		if t.Status != "blocked" || t.FeedFrom == 0 {
+			continue
+		}
+		ready := true
+		for _, dep := range t.DependsOn {
+			if d, ok := a.tasks[dep]; !ok || d.Status != "completed" {
+				ready = false // a failed one is resolveBlockedTasks' business
+			}
+		}
+		if !ready {
+			continue
+		}
+		f := feed{task: t, data: t.Data, from: t.FeedFrom}
+		if src, ok := a.tasks[t.FeedFrom]; ok {
+			f.outputs = append([]string(nil), src.Outputs...)
+		} else {
+			f.gone = true
+		}
+		feeds = append(feeds, f)
+	}
+	a.tasksMutex.Unlock()
+	if len(feeds) == 0 {
+		return false
+	}
+
+	data := make([]taskParams, len(feeds))
+	errs := make([]error, len(feeds))
+	for i, f := range feeds {
+		if f.gone {
+			errs[i] = fmt.Errorf("前置任务 #%d 不存在", f.from)
+			continue
+		}
+		data[i], errs[i] = feedItems(f.task.Type, f.data, f.from, f.outputs)
+	}
+
+	a.tasksMutex.Lock()
+	for i, f := range feeds {
+		t := f.task
+		if t.Status != "blocked" {
+			continue // cancelled meanwhile
+		}
+		if errs[i] != nil {
+			t.Status = "failed"
+			t.Error = errs[i].Error()
+		} else {
+			t.Data = data[i]
+			t.Status = "pending"
+		}
+	}
+	a.tasksMutex.Unlock()
+	return true
+}
+
+// feedItems returns a copy of data with the item list taken from outputs, the
+// files task from produced. Touches the files, so don't hold tasksMutex.
+func feedItems(taskType string, data interface{}, from int, outputs []string) (taskParams, error) {
+	var fed taskParams
+	switch p := data.(type) {
+	case *CreateShortcutsParams:
+		c := *p
+		c.Videos = nil
+		for _, path := range outputs {
+			if scanner.IsVideo(path) {
+				if info, err := os.Stat(path); err == nil {
+					c.Videos = append(c.Videos, VideoFile{Name: info.Name(), Path: path, Size: info.Size(), ParentFolder: filepath.Base(filepath.Dir(path))})
+				}
+			}
+		}
+		fed = &c
+	case *ConvertTxtParams:
+		c := *p
+		c.Files = outputFiles(outputs, ".txt")
+		fed = &c
+	case *PackImagesParams:
+		c := *p
+		c.Folders = nil
+		for _, path := range outputs {
+			if folder, ok := scanner.ImageFolder(path); ok {
+				c.Folders = append(c.Folders, folder)
+			}
+		}
+		fed = &c
+	default:
+		return nil, fmt.Errorf("%s 任务不能接收前置任务的输出", taskType)
+	}
+
+	if len(fed.itemKeys()) == 0 {
+		return nil, fmt.Errorf("前置任务 #%d 没有可供 %s 处理的输出", from, taskType)
+	}
+	return fed, nil
+}
+
+func outputFiles(outputs []string, ext string) []FileInfo {
+	var files []FileInfo
+	for _, path := range outputs {
+		if strings.ToLower(filepath.Ext(path)) == ext {
+			if info, err := os.Stat(path); err == nil {
+				files = append(files, FileInfo{Name: info.Name(), Path: path, Size: info.Size()})
+			}
+		}
+	}
+	return files
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ============ Priorities & Reordering ============
+
+// pendingTasks returns pending tasks in the order the dispatcher starts them:
+// highest priority first, then by queue position. Caller must hold tasksMutex.
+func (a *App) pendingTasks() []*Task {
+	var pending []*Task
+	for _, t := range a.tasks {
+		if t.Status == "pending" {
+			pending = append(pending, t)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Priority != pending[j].Priority {
+			return pending[i].Priority > pending[j].Priority
+		}
+		if pending[i].Order != pending[j].Order {
+			return pending[i].Order < pending[j].Order
+		}
+		return pending[i].ID < pending[j].ID
+	})
+	return pending
+}
+
+func (a *App) TaskQueueSetPriority(id int, priority int) error {
+	a.tasksMutex.Lock()
+	task, ok := a.tasks[id]
+	if !ok {
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("task %d not found", id)
+	}
+	task.Priority = priority
+	a.wakeDispatcher()
+	a.tasksMutex.Unlock()
+
+	a.saveTasks()
+	a.broadcastTaskList()
+	return nil
+}
+
+// TaskQueueMove puts a pending task at position (0 = next to run) among the
+// pending tasks. The task takes over the priority of the neighbour it lands
+// next to, so the dispatcher's priority order agrees with the new position.
+func (a *App) TaskQueueMove(id int, position int) error {
+	a.tasksMutex.Lock()
+	task, ok := a.tasks[id]
+	if !ok {
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("task %d not found", id)
+	}
+	if task.Status != "pending" {
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("task %d is %s, only pending tasks can be moved", id, task.Status)
+	}
+
+	var others []*Task
+	for _, t := range a.pendingTasks() {
+		if t.ID != id {
+			others = append(others, t)
+		}
+	}
+	if position < 0 {
+		position = 0
+	}
+	if position > len(others) {
+		position = len(others)
+	}
+
+	if len(others) > 0 {
+		if position < len(others) {
+			task.Priority = others[position].Priority
+		} else {
+			task.Priority = others[position-1].Priority
+		}
+	}
+
+	ordered := make([]*Task, 0, len(others)+1)
+	ordered = append(ordered, others[:position]...)
+	ordered = append(ordered, task)
+	ordered = append(ordered, others[position:]...)
+	for i, t := range ordered {
+		t.Order = i + 1
+	}
+	a.wakeDispatcher()
+	a.tasksMutex.Unlock()
+
+	a.saveTasks()
+	a.broadcastTaskList()
+	return nil
+}
+
+func (a *App) TaskQueueMoveToFront(id int) error {
+	return a.TaskQueueMove(id, 0)
+}
+
+func (a *App) TaskQueueMoveToBack(id int) error {
+	a.tasksMutex.Lock()
+	last := len(a.pendingTasks())
+	a.tasksMutex.Unlock()
+	return a.TaskQueueMove(id, last)
+}
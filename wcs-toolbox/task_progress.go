@@ -0,0 +1,84 @@
+package main
+
+import (
+	"time"
+)
+
+// ============ Byte Progress ============
+
+// byteRate tracks throughput over ~1s windows, smoothed so ETA doesn't jump around
+type byteRate struct {
+	windowStart time.Time
+	windowBytes int64
+}
+
+// setTaskBytesTotal announces how many bytes the task will process. From then on
+// Progress follows bytes instead of item counts.
+func (a *App) setTaskBytesTotal(task *Task, total int64) {
+	a.tasksMutex.Lock()
+	task.BytesTotal = total
+	task.BytesDone = 0
+	task.Throughput = 0
+	task.ETA = 0
+	task.rate = byteRate{windowStart: time.Now()}
+	a.tasksMutex.Unlock()
+}
+
+// addTaskBytesTotal grows the total when sizes are only learned on the way (downloads)
+func (a *App) addTaskBytesTotal(task *Task, n int64) {
+	a.tasksMutex.Lock()
+	if task.BytesTotal == 0 {
+		task.rate = byteRate{windowStart: time.Now()}
+	}
+	task.BytesTotal += n
+	a.tasksMutex.Unlock()
+}
+
+// addTaskBytes reports n more bytes processed. Safe to call from several goroutines.
+func (a *App) addTaskBytes(task *Task, n int64) {
+	if n <= 0 {
+		return
+	}
+	now := time.Now()
+
+	a.tasksMutex.Lock()
+	task.BytesDone += n
+	if task.BytesDone > task.BytesTotal {
+		task.BytesDone = task.BytesTotal
+	}
+	task.rate.windowBytes += n
+	if elapsed := now.Sub(task.rate.windowStart); elapsed >= time.Second {
+		inst := float64(task.rate.windowBytes) / elapsed.Seconds()
+		if task.Throughput == 0 {
+			task.Throughput = inst
+		} else {
+			task.Throughput = 0.7*task.Throughput + 0.3*inst
+		}
+		task.rate.windowStart = now
+		task.rate.windowBytes = 0
+	}
+	if task.Throughput > 0 {
+		task.ETA = int64(float64(task.BytesTotal-task.BytesDone) / task.Throughput)
+	}
+	if task.BytesTotal > 0 {
+		task.Progress = int(float64(task.BytesDone) / float64(task.BytesTotal) * 100)
+	}
+
+	a.scheduleSave()
+	a.tasksMutex.Unlock()
+
+	// Rate-limited by the event hub
+	a.broadcastTaskUpdate(task)
+}
+
+// taskReporter adapts a task to progress.Reporter for the tools in pkg/
+type taskReporter struct {
+	a    *App
+	task *Task
+}
+
+func (r taskReporter) AddTotal(n int64) { r.a.addTaskBytesTotal(r.task, n) }
+func (r taskReporter) Add(n int64)      { r.a.addTaskBytes(r.task, n) }
+func (r taskReporter) Logf(level string, format string, args ...interface{}) {
+	r.a.taskLogf(r.task, level, format, args...)
+}
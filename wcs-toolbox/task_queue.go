@@ -2,34 +2,52 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"runtime/debug"
 	"sort"
 	"time"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func (a *App) TaskQueueAdd(taskType string, data interface{}, name string) int {
+// TaskQueueAdd decodes data into the params struct of taskType and enqueues it.
+// Bad input is rejected here rather than crashing the worker later.
+func (a *App) TaskQueueAdd(taskType string, data interface{}, name string) (int, error) {
+	params, err := decodeTaskParams(taskType, data)
+	if err != nil {
+		return 0, err
+	}
+	if len(params.itemKeys()) == 0 {
+		return 0, fmt.Errorf("no items to process")
+	}
+	return a.addTask(&Task{
+		Type: taskType,
+		Name: name,
+		Data: params,
+	}), nil
+}
+
+func (a *App) addTask(task *Task) int {
 	a.tasksMutex.Lock()
 	defer a.tasksMutex.Unlock()
 
 	a.taskIdSeq++
 	id := a.taskIdSeq
 
-	task := &Task{
-		ID:        id,
-		Type:      taskType,
-		Name:      name,
-		Status:    "pending",
-		Data:      data, // cast later
-		CreatedAt: time.Now().UnixMilli(),
-		Progress:  0,
+	task.ID = id
+	task.Order = id
+	task.Status = "pending"
+	if len(task.DependsOn) > 0 {
+		task.Status = "blocked"
 	}
+	task.CreatedAt = time.Now().UnixMilli()
+	task.Progress = 0
 
 	a.tasks[id] = task
-	a.taskQueue <- id
+	a.wakeDispatcher()
 
-	go a.broadcastTaskList()
+	go a.saveTasks()
+	a.broadcastTaskList()
 
 	return id
 }
@@ -37,32 +55,196 @@ func (a *App) TaskQueueAdd(taskType string, data interface{}, name string) int {
 func (a *App) TaskQueueCancel(id int) {
 	a.tasksMutex.Lock()
 	if task, ok := a.tasks[id]; ok {
-		if task.Status == "pending" || task.Status == "running" {
+		if task.Status == "pending" || task.Status == "blocked" || task.Status == "running" || task.Status == "paused" {
 			task.Status = "cancelled"
 			if task.cancel != nil {
 				task.cancel()
 			}
 		}
 	}
+	a.wakeDispatcher() // dependents of this task get cancelled too
+	a.tasksMutex.Unlock()
+	a.saveTasks()
+	a.broadcastTaskList()
+}
+
+// TaskQueuePause holds a running task before its next item
+func (a *App) TaskQueuePause(id int) error {
+	a.tasksMutex.Lock()
+	task, ok := a.tasks[id]
+	if !ok {
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("task %d not found", id)
+	}
+	if task.Status != "running" {
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("task %d is %s, only running tasks can be paused", id, task.Status)
+	}
+	task.Status = "paused"
+	task.resume = make(chan struct{})
+	a.tasksMutex.Unlock()
+
+	a.taskLogf(task, "info", "已暂停")
+	a.saveTasks()
+	a.broadcastTaskUpdate(task)
+	return nil
+}
+
+// TaskQueueResume continues a paused task, or re-runs an interrupted, failed or
+// cancelled one. Items checkpointed in task.Completed are skipped, so only the
+// remaining/failed items are processed and the final result covers the whole task.
+func (a *App) TaskQueueResume(id int) error {
+	a.tasksMutex.Lock()
+	task, ok := a.tasks[id]
+	if !ok {
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("task %d not found", id)
+	}
+	if task.Status == "paused" {
+		task.Status = "running"
+		close(task.resume)
+		task.resume = nil
+		a.tasksMutex.Unlock()
+
+		a.taskLogf(task, "info", "继续执行")
+		a.saveTasks()
+		a.broadcastTaskUpdate(task)
+		return nil
+	}
+	if task.Status != "interrupted" && task.Status != "failed" && task.Status != "cancelled" {
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("task %d is %s and cannot be resumed", id, task.Status)
+	}
+	if task.running {
+		// Cancelled, but the old run hasn't returned yet; two runs would share the task
+		a.tasksMutex.Unlock()
+		return fmt.Errorf("task %d is still stopping, try again in a moment", id)
+	}
+	task.Status = "pending"
+	task.Progress = 0
+	task.Error = ""
+	task.Diagnostic = ""
+	task.Result = nil
+	a.wakeDispatcher()
 	a.tasksMutex.Unlock()
+
+	a.saveTasks()
 	a.broadcastTaskList()
+	return nil
+}
+
+// TaskQueueRetryFailed enqueues a new task of the same type containing only the items
+// that failed in task id, keeping all other options as they were.
+func (a *App) TaskQueueRetryFailed(id int) (int, error) {
+	return a.retryFailed(id, "重试", func(ErrorDetail) bool { return true }, nil)
 }
 
+// TaskQueueRetryWithPassword retries the archives of task id that failed for
+// want of a password, trying password before the stored list. remember also
+// adds it to the list.
+func (a *App) TaskQueueRetryWithPassword(id int, password string, remember bool) (int, error) {
+	if password == "" {
+		return 0, fmt.Errorf("password is required")
+	}
+	if remember {
+		if err := a.PasswordAdd(password); err != nil {
+			return 0, err
+		}
+	}
+	needsPassword := func(e ErrorDetail) bool { return e.NeedsPassword }
+	return a.retryFailed(id, "输入密码重试", needsPassword, func(retry *Task) error {
+		if _, ok := retry.Data.(*Convert7zParams); !ok {
+			return fmt.Errorf("only archive conversions take a password")
+		}
+		// Not in Data, which ends up in tasks.json, events and the API
+		retry.passwords = []string{password}
+		return nil
+	})
+}
+
+// retryFailed enqueues the items of task id whose errors match, after adjust
+// (if set) has had a go at the new task
+func (a *App) retryFailed(id int, label string, match func(ErrorDetail) bool, adjust func(*Task) error) (int, error) {
+	a.tasksMutex.Lock()
+	task, ok := a.tasks[id]
+	if !ok {
+		a.tasksMutex.Unlock()
+		return 0, fmt.Errorf("task %d not found", id)
+	}
+	taskType, name, data, result := task.Type, task.Name, task.Data, task.Result
+	a.tasksMutex.Unlock()
+
+	params, ok := data.(taskParams)
+	if !ok {
+		return 0, fmt.Errorf("invalid data format")
+	}
+
+	failedPaths := make(map[string]bool)
+	for _, e := range taskResultErrors(result) {
+		if e.Path != "" && match(e) {
+			failedPaths[e.Path] = true
+		}
+	}
+
+	retry := params.withItems(func(key string) bool { return failedPaths[key] })
+	count := len(retry.itemKeys())
+	if count == 0 {
+		return 0, fmt.Errorf("task %d has no failed items to retry", id)
+	}
+	newTask := &Task{
+		Type:     taskType,
+		Name:     fmt.Sprintf("%s (%s %d 项)", name, label, count),
+		Data:     retry,
+		ParentID: id,
+	}
+	if adjust != nil {
+		if err := adjust(newTask); err != nil {
+			return 0, err
+		}
+	}
+	return a.addTask(newTask), nil
+}
+
+// taskResultSummary reads the counts and errors of a task result, which is a
+// typed struct while the app runs and a plain map once reloaded from the journal.
+func taskResultSummary(result interface{}) TaskResult {
+	var r TaskResult
+	if result == nil {
+		return r
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return r
+	}
+	json.Unmarshal(raw, &r)
+	return r
+}
+
+func taskResultErrors(result interface{}) []ErrorDetail {
+	return taskResultSummary(result).Errors
+}
+
+// TaskQueueClearCompleted removes finished tasks from the queue. They stay in
+// the history (task_history.go); tasks that never ran are archived here.
 func (a *App) TaskQueueClearCompleted() {
+	var records []HistoryRecord
 	a.tasksMutex.Lock()
 	for id, task := range a.tasks {
-		if task.Status == "completed" || task.Status == "failed" || task.Status == "cancelled" {
+		if task.Status == "completed" || task.Status == "failed" || task.Status == "cancelled" || task.Status == "interrupted" {
+			records = append(records, newHistoryRecord(task))
 			delete(a.tasks, id)
 		}
 	}
 	a.tasksMutex.Unlock()
+	a.archiveTasks(records)
+	a.saveTasks()
 	a.broadcastTaskList()
 }
 
 func (a *App) TaskQueueGetAll() []Task {
 	a.tasksMutex.Lock()
 	defer a.tasksMutex.Unlock()
-	var tasks []Task
+	tasks := []Task{}
 	for _, t := range a.tasks {
 		tasks = append(tasks, *t)
 	}
@@ -73,63 +255,102 @@ func (a *App) TaskQueueGetAll() []Task {
 	return tasks
 }
 
+// broadcastTaskList and broadcastTaskUpdate only mark changes; the event hub
+// (event_hub.go) coalesces them and emits diffs at a capped rate
 func (a *App) broadcastTaskList() {
-	// Slight delay to allow status updates to propagate
-	time.Sleep(50 * time.Millisecond)
-	tasks := a.TaskQueueGetAll()
-	runtime.EventsEmit(a.ctx, "task-list-update", tasks)
+	a.events.TouchAll()
 }
 
-func (a *App) processTasks() {
-	// Worker pool size 1 to avoid overwhelming disk I/O, or 2 allowed.
-	// Electron version had maxConcurrent = 2.
-	limit := 2
-	sem := make(chan struct{}, limit)
-
-	for id := range a.taskQueue {
-		sem <- struct{}{}
-		go func(taskId int) {
-			defer func() { <-sem }()
-			a.runTask(taskId)
-		}(id)
+// snapshotTasks copies the given tasks (all when ids is nil) for the event hub
+func (a *App) snapshotTasks(ids []int) []Task {
+	a.tasksMutex.Lock()
+	defer a.tasksMutex.Unlock()
+	var tasks []Task
+	if ids == nil {
+		for _, t := range a.tasks {
+			tasks = append(tasks, *t)
+		}
+		return tasks
 	}
+	for _, id := range ids {
+		if t, ok := a.tasks[id]; ok {
+			tasks = append(tasks, *t)
+		}
+	}
+	return tasks
 }
 
-func (a *App) runTask(taskId int) {
+// TaskQueuePauseAll stops the dispatcher from starting new tasks; running ones continue
+func (a *App) TaskQueuePauseAll() {
 	a.tasksMutex.Lock()
-	task, exists := a.tasks[taskId]
-	if !exists || task.Status == "cancelled" {
-		a.tasksMutex.Unlock()
-		return
-	}
-	task.Status = "running"
-	ctx, cancel := context.WithCancel(context.Background())
-	task.cancel = cancel
+	a.queuePaused = true
 	a.tasksMutex.Unlock()
+	a.emitEvent("task-queue-paused", true)
+}
 
-	a.broadcastTaskUpdate(task)
+func (a *App) TaskQueueResumeAll() {
+	a.tasksMutex.Lock()
+	a.queuePaused = false
+	a.wakeDispatcher()
+	a.tasksMutex.Unlock()
+	a.emitEvent("task-queue-paused", false)
+}
 
-	var err error
-	var result interface{}
+func (a *App) TaskQueueIsPaused() bool {
+	a.tasksMutex.Lock()
+	defer a.tasksMutex.Unlock()
+	return a.queuePaused
+}
 
-	// Dispatch based on type
-	switch task.Type {
-	case "create-shortcuts":
-		result, err = a.handleCreateShortcuts(ctx, task)
-	case "convert-7z-to-zip":
-		result, err = a.handleConvert7z(ctx, task)
-	case "pack-images":
-		result, err = a.handlePackImages(ctx, task)
-	case "convert-txt-to-epub":
-		result, err = a.handleConvertTxtToEpub(ctx, task)
-	default:
-		err = fmt.Errorf("unknown task type: %s", task.Type)
+// waitIfPaused is called by handlers between items. It blocks while the task is
+// paused and returns an error once the task is cancelled.
+func (a *App) waitIfPaused(ctx context.Context, task *Task) error {
+	a.tasksMutex.Lock()
+	ch := task.resume
+	a.tasksMutex.Unlock()
+	if ch != nil {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+		}
+	}
+	return ctx.Err()
+}
+
+func (a *App) processTasks() {
+	// Woken on every add/finish/resume/limit change; dispatchTasks decides
+	// what may start under the per-class limits (task_concurrency.go).
+	for range a.taskWake {
+		a.dispatchTasks()
 	}
+}
+
+// runTask executes a task already claimed by dispatchTasks
+func (a *App) runTask(ctx context.Context, task *Task) {
+	class := taskResourceClass(task.Type)
+	defer a.releaseSlot(class)
+
+	a.saveTasks()
+	a.broadcastTaskUpdate(task)
+
+	if n := len(a.completedItems(task)); n > 0 {
+		a.taskLogf(task, "info", "开始执行，跳过已完成的 %d 项", n)
+	} else {
+		a.taskLogf(task, "info", "开始执行")
+	}
+	result, err := a.executeTask(ctx, task)
 
 	// Update Final Status
 	a.tasksMutex.Lock()
-	// Check if cancelled again just in case
-	if ctx.Err() != nil || task.Status == "cancelled" {
+	task.resume = nil
+	var pe *taskPanicError
+	if errors.As(err, &pe) {
+		// A crashed handler fails only its own task, even if it was being cancelled
+		task.Status = "failed"
+		task.Error = pe.Error()
+		task.Diagnostic = string(pe.stack)
+	} else if ctx.Err() != nil || task.Status == "cancelled" {
+		// Check if cancelled again just in case
 		task.Status = "cancelled" // Ensure cancelled state
 	} else if err != nil {
 		task.Status = "failed"
@@ -139,13 +360,71 @@ func (a *App) runTask(taskId int) {
 		task.Result = result
 		task.Progress = 100
 	}
+	task.FinishedAt = time.Now().UnixMilli()
+	task.running = false
+	status, errMsg := task.Status, task.Error
+	record := newHistoryRecord(task)
 	a.tasksMutex.Unlock()
 
+	switch status {
+	case "failed":
+		a.taskLogf(task, "error", "任务失败: %s", errMsg)
+	case "cancelled":
+		a.taskLogf(task, "warn", "任务已取消")
+	default:
+		a.taskLogf(task, "info", "任务完成")
+	}
+	a.saveTasks()
+	a.archiveTasks([]HistoryRecord{record})
+
 	a.broadcastTaskUpdate(task)
 	// Also update list
 	a.broadcastTaskList()
 }
 
+// taskPanicError carries a recovered handler panic and where it happened
+type taskPanicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *taskPanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// executeTask runs the handler for task.Type. A panic is recovered and returned
+// as *taskPanicError so it can't take down the app and the other running tasks.
+func (a *App) executeTask(ctx context.Context, task *Task) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = &taskPanicError{value: r, stack: debug.Stack()}
+		}
+	}()
+
+	// Dispatch based on type
+	switch task.Type {
+	case "create-shortcuts":
+		result, err = a.handleCreateShortcuts(ctx, task)
+	case "convert-7z-to-zip":
+		result, err = a.handleConvertArchive(ctx, task)
+	case "pack-images":
+		result, err = a.handlePackImages(ctx, task)
+	case "convert-txt-to-epub":
+		result, err = a.handleConvertTxtToEpub(ctx, task)
+	case "crawl-gallery":
+		result, err = a.handleCrawlGallery(ctx, task)
+	default:
+		err = fmt.Errorf("unknown task type: %s", task.Type)
+	}
+	return result, err
+}
+
 func (a *App) broadcastTaskUpdate(task *Task) {
-	runtime.EventsEmit(a.ctx, "task-update", task)
+	a.events.Touch(task.ID)
+}
+
+// emitEvent sends a non-task event (tool progress, logs, queue state)
+func (a *App) emitEvent(name string, data interface{}) {
+	a.events.Emit(name, data)
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ============ Task Persistence ============
+
+// taskSnapshot is the on-disk journal of the task queue.
+type taskSnapshot struct {
+	Seq   int          `json:"seq"`
+	Tasks []storedTask `json:"tasks"`
+}
+
+// storedTask adds what Task keeps out of its regular JSON (events, GetAll)
+type storedTask struct {
+	Task
+	Logs []TaskLogEntry `json:"logs,omitempty"`
+}
+
+// appDataDir returns (and creates) the per-user directory for local state.
+func appDataDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, "wcs-toolbox")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// writeFileAtomic writes to a temp file first so a crash never leaves a half-written store.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadTasks restores the journal; pending tasks are picked up by the dispatcher as usual
+func (a *App) loadTasks() {
+	dir, err := appDataDir()
+	if err != nil {
+		return
+	}
+	a.taskStorePath = filepath.Join(dir, "tasks.json")
+
+	raw, err := os.ReadFile(a.taskStorePath)
+	if err != nil {
+		return
+	}
+	var snap taskSnapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return
+	}
+
+	a.tasksMutex.Lock()
+	defer a.tasksMutex.Unlock()
+
+	a.taskIdSeq = snap.Seq
+	for i := range snap.Tasks {
+		t := snap.Tasks[i].Task
+		t.Logs = snap.Tasks[i].Logs
+		switch t.Status {
+		case "running", "paused":
+			// The process died under it; let the user decide whether to resume
+			t.Status = "interrupted"
+		}
+		// Data comes back as a plain map; restore the typed params handlers expect
+		if params, err := decodeTaskParams(t.Type, t.Data); err == nil {
+			t.Data = params
+		} else if t.Status == "pending" || t.Status == "blocked" || t.Status == "interrupted" {
+			t.Status = "failed"
+			t.Error = err.Error()
+		}
+		if t.Order == 0 {
+			t.Order = t.ID // journals written before ordering existed
+		}
+		if t.ID > a.taskIdSeq {
+			a.taskIdSeq = t.ID
+		}
+		a.tasks[t.ID] = &t
+	}
+}
+
+func (a *App) saveTasks() {
+	if a.taskStorePath == "" {
+		return
+	}
+
+	// Hold storeMutex across snapshot and write so an older snapshot never overwrites a newer one
+	a.storeMutex.Lock()
+	defer a.storeMutex.Unlock()
+
+	a.tasksMutex.Lock()
+	snap := taskSnapshot{Seq: a.taskIdSeq}
+	for _, t := range a.tasks {
+		snap.Tasks = append(snap.Tasks, storedTask{Task: *t, Logs: append([]TaskLogEntry(nil), t.Logs...)})
+	}
+	a.saveTimer = nil
+	a.tasksMutex.Unlock()
+
+	sort.Slice(snap.Tasks, func(i, j int) bool {
+		return snap.Tasks[i].ID < snap.Tasks[j].ID
+	})
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return
+	}
+	writeFileAtomic(a.taskStorePath, data)
+}
+
+// scheduleSave coalesces frequent changes (progress) into one write per second.
+// Caller must hold tasksMutex.
+func (a *App) scheduleSave() {
+	if a.saveTimer != nil {
+		return
+	}
+	a.saveTimer = time.AfterFunc(time.Second, a.saveTasks)
+}
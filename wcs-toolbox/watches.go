@@ -0,0 +1,535 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ============ Watch Folders ============
+
+// Watch keeps an eye on Folder and enqueues new items with Preset's options
+// once they settle, i.e. their size stayed the same for SettleSeconds.
+type Watch struct {
+	ID              int                 `json:"id"`
+	Name            string              `json:"name"`
+	Folder          string              `json:"folder"`
+	Preset          string              `json:"preset"`
+	Enabled         bool                `json:"enabled"`
+	SettleSeconds   int                 `json:"settleSeconds"`   // size must stay the same this long
+	DebounceSeconds int                 `json:"debounceSeconds"` // quiet time after file events before scanning
+	Ignore          []string            `json:"ignore"`          // glob patterns matched against names and relative paths, e.g. *.part
+	LastError       string              `json:"lastError,omitempty"`
+	History         []WatchHistoryEntry `json:"history"`             // newest first
+	Processed       []string            `json:"processed,omitempty"` // items already enqueued, pruned to what the last scan found
+	runner          *watchRunner
+}
+
+type WatchHistoryEntry struct {
+	Path   string `json:"path"`
+	TaskID int    `json:"taskId,omitempty"`
+	Time   int64  `json:"time"`
+}
+
+type watchSnapshot struct {
+	Seq     int      `json:"seq"`
+	Watches []*Watch `json:"watches"`
+}
+
+const (
+	defaultWatchSettle   = 10
+	defaultWatchDebounce = 2
+	maxWatchHistory      = 200
+	// Rescan now and then anyway; network drives don't always report changes
+	watchRescanInterval = time.Minute
+)
+
+// watchRunner is the goroutine side of an enabled watch
+type watchRunner struct {
+	stop    chan struct{}
+	pending map[string]settling // only touched by the runner goroutine
+}
+
+type settling struct {
+	size  int64
+	since time.Time
+}
+
+func (a *App) loadWatches() {
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+
+	a.watches = make(map[int]*Watch)
+	dir, err := appDataDir()
+	if err != nil {
+		return
+	}
+	a.watchesPath = filepath.Join(dir, "watches.json")
+
+	raw, err := os.ReadFile(a.watchesPath)
+	if err != nil {
+		return
+	}
+	var snap watchSnapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return
+	}
+	a.watchIdSeq = snap.Seq
+	for _, w := range snap.Watches {
+		if w.ID > a.watchIdSeq {
+			a.watchIdSeq = w.ID
+		}
+		a.watches[w.ID] = w
+	}
+}
+
+// startWatches starts every enabled watch; files that arrived while the app
+// was closed are picked up by the first scan
+func (a *App) startWatches() {
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	for _, w := range a.watches {
+		if w.Enabled && w.runner == nil {
+			a.startWatchLocked(w)
+		}
+	}
+}
+
+func (a *App) stopWatches() {
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	for _, w := range a.watches {
+		stopWatchLocked(w)
+	}
+}
+
+// saveWatchesLocked writes the watches; caller must hold watchesMutex
+func (a *App) saveWatchesLocked() error {
+	if a.watchesPath == "" {
+		return nil
+	}
+	snap := watchSnapshot{Seq: a.watchIdSeq, Watches: a.watchListLocked()}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(a.watchesPath, data)
+}
+
+func (a *App) watchListLocked() []*Watch {
+	list := make([]*Watch, 0, len(a.watches))
+	for _, w := range a.watches {
+		list = append(list, w)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func (a *App) watchCopiesLocked() []Watch {
+	list := []Watch{}
+	for _, w := range a.watchListLocked() {
+		c := *w
+		c.History = append([]WatchHistoryEntry{}, w.History...)
+		c.Processed = nil
+		c.runner = nil
+		list = append(list, c)
+	}
+	return list
+}
+
+// watchesChangedLocked saves and tells the frontend; caller must hold watchesMutex
+func (a *App) watchesChangedLocked() error {
+	err := a.saveWatchesLocked()
+	a.emitEvent("watch-list-update", a.watchCopiesLocked())
+	return err
+}
+
+func (a *App) normalizeWatch(w Watch) (Watch, error) {
+	w.Folder = strings.TrimSpace(w.Folder)
+	if w.Folder == "" {
+		return w, fmt.Errorf("folder is required")
+	}
+	if info, err := os.Stat(w.Folder); err != nil {
+		return w, err
+	} else if !info.IsDir() {
+		return w, fmt.Errorf("%s is not a folder", w.Folder)
+	}
+	p, err := a.PresetGet(w.Preset)
+	if err != nil {
+		return w, err
+	}
+	params, err := decodeTaskParams(p.Type, p.Data)
+	if err != nil {
+		return w, err
+	}
+	if _, err := scanTaskItems(params, w.Folder); err != nil {
+		return w, err
+	}
+
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" {
+		w.Name = filepath.Base(w.Folder)
+	}
+	if w.SettleSeconds <= 0 {
+		w.SettleSeconds = defaultWatchSettle
+	}
+	if w.DebounceSeconds <= 0 {
+		w.DebounceSeconds = defaultWatchDebounce
+	}
+	var ignore []string
+	for _, pattern := range w.Ignore {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return w, fmt.Errorf("invalid ignore pattern %q: %v", pattern, err)
+		}
+		ignore = append(ignore, pattern)
+	}
+	w.Ignore = ignore
+	return w, nil
+}
+
+func (a *App) WatchList() []Watch {
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	return a.watchCopiesLocked()
+}
+
+// WatchAdd registers a watch. What the folder already holds counts as
+// processed; only items that appear later are enqueued.
+func (a *App) WatchAdd(w Watch) (Watch, error) {
+	w, err := a.normalizeWatch(w)
+	if err != nil {
+		return Watch{}, err
+	}
+	_, existing, err := a.watchScan(w)
+	if err != nil {
+		return Watch{}, err
+	}
+
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	a.watchIdSeq++
+	w.ID = a.watchIdSeq
+	w.LastError, w.History, w.runner = "", nil, nil
+	w.Processed = existing.itemKeys()
+	a.watches[w.ID] = &w
+	if w.Enabled {
+		a.startWatchLocked(&w)
+	}
+	c := w
+	c.Processed, c.runner = nil, nil
+	return c, a.watchesChangedLocked()
+}
+
+// WatchUpdate changes a watch's settings, keeping its history. Moving it to
+// another folder skips what that folder already holds, as WatchAdd does.
+func (a *App) WatchUpdate(id int, w Watch) (Watch, error) {
+	w, err := a.normalizeWatch(w)
+	if err != nil {
+		return Watch{}, err
+	}
+	_, existing, err := a.watchScan(w)
+	if err != nil {
+		return Watch{}, err
+	}
+
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	old, ok := a.watches[id]
+	if !ok {
+		return Watch{}, fmt.Errorf("watch %d not found", id)
+	}
+	stopWatchLocked(old)
+	w.ID = id
+	w.History, w.Processed, w.runner = old.History, old.Processed, nil
+	if w.Folder != old.Folder {
+		w.Processed = existing.itemKeys()
+	}
+	*old = w
+	if old.Enabled {
+		a.startWatchLocked(old)
+	}
+	w.Processed = nil
+	return w, a.watchesChangedLocked()
+}
+
+func (a *App) WatchDelete(id int) error {
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	w, ok := a.watches[id]
+	if !ok {
+		return fmt.Errorf("watch %d not found", id)
+	}
+	stopWatchLocked(w)
+	delete(a.watches, id)
+	return a.watchesChangedLocked()
+}
+
+func (a *App) WatchClearHistory(id int) error {
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	w, ok := a.watches[id]
+	if !ok {
+		return fmt.Errorf("watch %d not found", id)
+	}
+	w.History = nil
+	return a.watchesChangedLocked()
+}
+
+// startWatchLocked starts the runner of w; caller must hold watchesMutex
+func (a *App) startWatchLocked(w *Watch) {
+	r := &watchRunner{stop: make(chan struct{}), pending: make(map[string]settling)}
+	w.runner = r
+	go a.runWatch(w.ID, w.Folder, r)
+}
+
+func stopWatchLocked(w *Watch) {
+	if w.runner != nil {
+		close(w.runner.stop)
+		w.runner = nil
+	}
+}
+
+// runWatch turns file events into debounced scans, and keeps checking while
+// some item is still settling
+func (a *App) runWatch(id int, folder string, r *watchRunner) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		a.setWatchError(id, r, err.Error())
+	} else {
+		defer fw.Close()
+		addWatchDirs(fw, folder)
+	}
+	var events chan fsnotify.Event
+	var errs chan error
+	if fw != nil {
+		events, errs = fw.Events, fw.Errors
+	}
+
+	check := time.NewTimer(0) // first scan right away
+	defer check.Stop()
+	rescan := time.NewTicker(watchRescanInterval)
+	defer rescan.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			// fsnotify isn't recursive; follow new subfolders
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					addWatchDirs(fw, ev.Name)
+				}
+			}
+			check.Reset(time.Duration(a.watchDebounce(id)) * time.Second)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			a.setWatchError(id, r, err.Error())
+		case <-rescan.C:
+			check.Reset(0)
+		case <-check.C:
+			if a.checkWatch(id, r) {
+				check.Reset(time.Second)
+			}
+		}
+	}
+}
+
+func addWatchDirs(fw *fsnotify.Watcher, root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			fw.Add(path)
+		}
+		return nil
+	})
+}
+
+func (a *App) watchDebounce(id int) int {
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	if w, ok := a.watches[id]; ok {
+		return w.DebounceSeconds
+	}
+	return defaultWatchDebounce
+}
+
+// setWatchError records err unless r has been replaced in the meantime
+func (a *App) setWatchError(id int, r *watchRunner, msg string) {
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	if w, ok := a.watches[id]; ok && w.runner == r && w.LastError != msg {
+		w.LastError = msg
+		a.watchesChangedLocked()
+	}
+}
+
+// watchScan returns what w's preset would pick up in its folder, minus
+// ignored items, as params ready to enqueue
+func (a *App) watchScan(w Watch) (string, taskParams, error) {
+	p, err := a.PresetGet(w.Preset)
+	if err != nil {
+		return "", nil, err
+	}
+	params, err := decodeTaskParams(p.Type, p.Data)
+	if err != nil {
+		return "", nil, err
+	}
+	params, err = scanTaskItems(params, w.Folder)
+	if err != nil {
+		return "", nil, err
+	}
+	return p.Type, params.withItems(func(key string) bool { return !w.ignored(key) }), nil
+}
+
+func (w Watch) ignored(path string) bool {
+	rel, err := filepath.Rel(w.Folder, path)
+	if err != nil {
+		rel = path
+	}
+	for _, pattern := range w.Ignore {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.ToSlash(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// itemSizes maps each item of params to its size. Image folders also count
+// their images so an empty file being copied in still reads as a change.
+func itemSizes(params taskParams) map[string]int64 {
+	sizes := make(map[string]int64)
+	switch p := params.(type) {
+	case *CreateShortcutsParams:
+		for _, v := range p.Videos {
+			sizes[v.Path] = v.Size
+		}
+	case *Convert7zParams:
+		for _, f := range p.Files {
+			sizes[f.Path] = f.Size
+		}
+	case *PackImagesParams:
+		for _, f := range p.Folders {
+			sizes[f.Path] = f.TotalSize + int64(f.ImageCount)
+		}
+	case *ConvertTxtParams:
+		for _, f := range p.Files {
+			sizes[f.Path] = f.Size
+		}
+	}
+	return sizes
+}
+
+// checkWatch scans once and enqueues the items that have settled. Returns
+// true while some new item is still settling.
+func (a *App) checkWatch(id int, r *watchRunner) bool {
+	a.watchesMutex.Lock()
+	w, ok := a.watches[id]
+	if !ok || w.runner != r {
+		a.watchesMutex.Unlock()
+		return false
+	}
+	snap := *w
+	processed := make(map[string]bool, len(w.Processed))
+	for _, k := range w.Processed {
+		processed[k] = true
+	}
+	a.watchesMutex.Unlock()
+
+	taskType, params, err := a.watchScan(snap)
+	if err != nil {
+		a.setWatchError(id, r, err.Error())
+		return false
+	}
+	sizes := itemSizes(params)
+
+	now := time.Now()
+	settle := time.Duration(snap.SettleSeconds) * time.Second
+	ready := make(map[string]bool)
+	for key, size := range sizes {
+		if processed[key] {
+			continue
+		}
+		s, seen := r.pending[key]
+		if !seen || s.size != size {
+			r.pending[key] = settling{size: size, since: now}
+			continue
+		}
+		if now.Sub(s.since) >= settle {
+			ready[key] = true
+		}
+	}
+	for key := range r.pending {
+		if _, ok := sizes[key]; !ok || ready[key] {
+			delete(r.pending, key) // gone, or about to be enqueued
+		}
+	}
+
+	taskID := 0
+	if len(ready) > 0 {
+		taskID = a.addTask(&Task{
+			Type: taskType,
+			Name: fmt.Sprintf("%s: %s", snap.Name, now.Format("2006-01-02 15:04")),
+			Data: params.withItems(func(key string) bool { return ready[key] }),
+		})
+	}
+
+	a.watchesMutex.Lock()
+	defer a.watchesMutex.Unlock()
+	if w, ok = a.watches[id]; !ok || w.runner != r {
+		return false
+	}
+	changed := w.LastError != ""
+	w.LastError = ""
+
+	// Keep only what is still there, so the list doesn't grow forever
+	var kept []string
+	for key := range sizes {
+		if processed[key] || ready[key] {
+			kept = append(kept, key)
+		}
+	}
+	sort.Strings(kept)
+	if len(kept) != len(w.Processed) {
+		changed = true
+	}
+	w.Processed = kept
+
+	var added []WatchHistoryEntry
+	for _, key := range params.itemKeys() {
+		if ready[key] {
+			added = append(added, WatchHistoryEntry{Path: key, TaskID: taskID, Time: now.UnixMilli()})
+		}
+	}
+	if len(added) > 0 {
+		w.History = append(added, w.History...)
+		if len(w.History) > maxWatchHistory {
+			w.History = w.History[:maxWatchHistory]
+		}
+		changed = true
+	}
+	if changed {
+		a.watchesChangedLocked()
+	}
+	return len(r.pending) > 0
+}